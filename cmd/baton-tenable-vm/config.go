@@ -1,7 +1,11 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/conductorone/baton-sdk/pkg/field"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/conductorone/baton-tenable-vm/pkg/connector"
 	"github.com/spf13/viper"
 )
 
@@ -16,10 +20,58 @@ var (
 		field.WithDescription("The Tenable API key connect to the Tenable API"),
 		field.WithRequired(true),
 	)
+	BaseURLField = field.StringField(
+		"base-url",
+		field.WithDescription("The Tenable VM API base URL, or a region alias: cloud, fedcloud, fedramp (same as fedcloud)"),
+		field.WithDefaultValue(client.DefaultBaseURL),
+	)
+	MaxRetriesField = field.IntField(
+		"max-retries",
+		field.WithDescription("How many times a throttled or temporarily failing request is retried"),
+		field.WithDefaultValue(client.DefaultMaxRetries),
+	)
+	RequestsPerSecondField = field.IntField(
+		"requests-per-second",
+		field.WithDescription("Maximum number of requests sent to Tenable per second, 0 for no limit"),
+		field.WithDefaultValue(0),
+	)
+	MaxConcurrentRequestsField = field.IntField(
+		"max-concurrent-requests",
+		field.WithDescription("Maximum number of requests in flight to Tenable at once, 0 for no limit"),
+		field.WithDefaultValue(0),
+	)
+	TransferOwnershipToField = field.StringField(
+		"transfer-ownership-to",
+		field.WithDescription("UUID or username of the user who takes over the scans, policies and managed credentials of deleted users"),
+	)
+	PasswordMinLengthField = field.IntField(
+		"password-min-length",
+		field.WithDescription("Minimum length of generated passwords, from 12 to 128"),
+		field.WithDefaultValue(connector.MinPasswordLength),
+	)
+	PasswordExcludedCharactersField = field.StringField(
+		"password-excluded-characters",
+		field.WithDescription("Characters never used in generated passwords"),
+	)
+	ForceDeleteAssignedRolesField = field.BoolField(
+		"force-delete-assigned-roles",
+		field.WithDescription("Delete custom roles that are still assigned to users, taking the role away from them first"),
+	)
 	// ConfigurationFields defines the external configuration required for the
 	// connector to run. Note: these fields can be marked as optional or
 	// required.
-	ConfigurationFields = []field.SchemaField{SecretKeyField, AccessKeyField}
+	ConfigurationFields = []field.SchemaField{
+		SecretKeyField,
+		AccessKeyField,
+		BaseURLField,
+		MaxRetriesField,
+		RequestsPerSecondField,
+		MaxConcurrentRequestsField,
+		TransferOwnershipToField,
+		PasswordMinLengthField,
+		PasswordExcludedCharactersField,
+		ForceDeleteAssignedRolesField,
+	}
 )
 
 // ValidateConfig is run after the configuration is loaded, and should return an
@@ -27,5 +79,24 @@ var (
 // needs to perform extra validations that cannot be encoded with configuration
 // parameters.
 func ValidateConfig(v *viper.Viper) error {
-	return nil
+	if _, err := client.ResolveBaseURL(v.GetString(BaseURLField.FieldName)); err != nil {
+		return err
+	}
+	for _, f := range []field.SchemaField{MaxRetriesField, RequestsPerSecondField, MaxConcurrentRequestsField} {
+		if v.GetInt(f.FieldName) < 0 {
+			return fmt.Errorf("%s must not be negative", f.FieldName)
+		}
+	}
+	return passwordPolicy(v).Validate()
+}
+
+// passwordPolicy builds the policy generated passwords must satisfy from the password-* fields, keeping the
+// defaults for the fields that are not set.
+func passwordPolicy(v *viper.Viper) connector.PasswordPolicy {
+	policy := connector.DefaultPasswordPolicy()
+	if v.IsSet(PasswordMinLengthField.FieldName) {
+		policy.MinLength = v.GetInt(PasswordMinLengthField.FieldName)
+	}
+	policy.ExcludedCharacters = v.GetString(PasswordExcludedCharactersField.FieldName)
+	return policy
 }
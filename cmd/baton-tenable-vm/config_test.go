@@ -13,7 +13,114 @@ func TestConfigs(t *testing.T) {
 	)
 
 	testCases := []test.TestCase{
-		// Add test cases here.
+		{
+			Configs: map[string]string{
+				"access-key": "access",
+				"secret-key": "secret",
+			},
+			IsValid: true,
+			Message: "default base url",
+		},
+		{
+			Configs: map[string]string{
+				"access-key": "access",
+				"secret-key": "secret",
+				"base-url":   "fedcloud",
+			},
+			IsValid: true,
+			Message: "region alias",
+		},
+		{
+			Configs: map[string]string{
+				"access-key": "access",
+				"secret-key": "secret",
+				"base-url":   "FedRAMP",
+			},
+			IsValid: true,
+			Message: "fedramp region alias",
+		},
+		{
+			Configs: map[string]string{
+				"access-key": "access",
+				"secret-key": "secret",
+				"base-url":   "http://127.0.0.1:8080/",
+			},
+			IsValid: true,
+			Message: "explicit base url",
+		},
+		{
+			Configs: map[string]string{
+				"access-key": "access",
+				"secret-key": "secret",
+				"base-url":   "ftp://cloud.tenable.com",
+			},
+			IsValid: false,
+			Message: "unsupported scheme",
+		},
+		{
+			Configs: map[string]string{
+				"access-key": "access",
+				"secret-key": "secret",
+				"base-url":   "moon",
+			},
+			IsValid: false,
+			Message: "unknown region alias",
+		},
+		{
+			Configs: map[string]string{
+				"access-key":              "access",
+				"secret-key":              "secret",
+				"max-retries":             "5",
+				"requests-per-second":     "10",
+				"max-concurrent-requests": "4",
+			},
+			IsValid: true,
+			Message: "request limits",
+		},
+		{
+			Configs: map[string]string{
+				"access-key":          "access",
+				"secret-key":          "secret",
+				"requests-per-second": "-1",
+			},
+			IsValid: false,
+			Message: "negative request limit",
+		},
+		{
+			Configs: map[string]string{
+				"access-key":                   "access",
+				"secret-key":                   "secret",
+				"password-min-length":          "32",
+				"password-excluded-characters": "\"'`\\",
+			},
+			IsValid: true,
+			Message: "password policy",
+		},
+		{
+			Configs: map[string]string{
+				"access-key":          "access",
+				"secret-key":          "secret",
+				"password-min-length": "8",
+			},
+			IsValid: false,
+			Message: "password shorter than the tenable minimum",
+		},
+		{
+			Configs: map[string]string{
+				"access-key":                   "access",
+				"secret-key":                   "secret",
+				"password-excluded-characters": "0123456789",
+			},
+			IsValid: false,
+			Message: "required password class fully excluded",
+		},
+		{
+			Configs: map[string]string{
+				"base-url": "cloud",
+			},
+			IsValid: false,
+			Message: "missing keys",
+		},
 	}
 
 	test.ExerciseTestCases(t, configurationSchema, ValidateConfig, testCases)
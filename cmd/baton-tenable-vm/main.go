@@ -9,6 +9,7 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/conductorone/baton-sdk/pkg/types"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
 	"github.com/conductorone/baton-tenable-vm/pkg/connector"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/spf13/viper"
@@ -48,7 +49,25 @@ func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, e
 		return nil, err
 	}
 
-	cb, err := connector.New(ctx, v.GetString(AccessKeyField.FieldName), v.GetString(SecretKeyField.FieldName))
+	retryPolicy := client.DefaultRetryPolicy()
+	retryPolicy.MaxRetries = v.GetInt(MaxRetriesField.FieldName)
+
+	cb, err := connector.New(
+		ctx,
+		v.GetString(BaseURLField.FieldName),
+		v.GetString(AccessKeyField.FieldName),
+		v.GetString(SecretKeyField.FieldName),
+		connector.WithClientOptions(
+			client.WithRetryPolicy(retryPolicy),
+			client.WithRequestLimits(
+				v.GetInt(MaxConcurrentRequestsField.FieldName),
+				v.GetInt(RequestsPerSecondField.FieldName),
+			),
+		),
+		connector.WithOwnershipTransferTo(v.GetString(TransferOwnershipToField.FieldName)),
+		connector.WithPasswordPolicy(passwordPolicy(v)),
+		connector.WithForceRoleDeletion(v.GetBool(ForceDeleteAssignedRolesField.FieldName)),
+	)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
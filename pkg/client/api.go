@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// TenableAPI is the Tenable VM API surface used by the connector. TenableVMClient implements it against the
+// real service, tests substitute an in-memory fake.
+type TenableAPI interface {
+	GetSession(ctx context.Context) (*User, error)
+
+	GetUsers(ctx context.Context) ([]User, annotations.Annotations, error)
+	GetUserDetails(ctx context.Context, userId string) (*User, error)
+	CreateUser(ctx context.Context, newUser NewUser) (*User, error)
+	UpdateUser(ctx context.Context, userId string, body UserUpdateReqBody) (*User, error)
+	SetUserEnabled(ctx context.Context, userId string, enabled bool) error
+	ChangeUserPassword(ctx context.Context, userId string, password string) error
+	GenerateAPIKeys(ctx context.Context, userId string) (*APIKeys, error)
+	GetUserAuthorizations(ctx context.Context, userId string) (*UserAuthorizations, error)
+	UpdateUserAuthorizations(ctx context.Context, userId string, authorizations UserAuthorizations) error
+	DeleteUser(ctx context.Context, userId string) error
+
+	GetRoles(ctx context.Context) ([]*RoleDetails, annotations.Annotations, error)
+	CreateRole(ctx context.Context, role RoleReqBody) (*RoleDetails, error)
+	UpdateRole(ctx context.Context, roleUUID string, role RoleReqBody) (*RoleDetails, error)
+	DeleteRole(ctx context.Context, roleUUID string) error
+	GetUserRoles(ctx context.Context, userUUID string) (*UserRole, error)
+	UpdateUserRoles(ctx context.Context, userUUID string, roleUUIDs []string) (*UserRole, error)
+
+	GetGroups(ctx context.Context) ([]Group, annotations.Annotations, error)
+	CreateGroup(ctx context.Context, name string) (*Group, error)
+	DeleteGroup(ctx context.Context, groupId string) error
+	GetGroupMembers(ctx context.Context, groupId string) ([]User, annotations.Annotations, error)
+	CreateUserGroupMembership(ctx context.Context, groupId string, userId string, add bool) error
+	DeleteUserGroupMembership(ctx context.Context, groupId string, userId string) error
+
+	ListPermissions(ctx context.Context, opts PageOptions) ([]Permission, string, annotations.Annotations, error)
+	GetPermissionDetails(ctx context.Context, uuid string) (*Permission, error)
+	UpdatePermission(ctx context.Context, updatedPermission *Permission) error
+	CreatePermission(ctx context.Context, permission *Permission) (*Permission, error)
+	DeletePermission(ctx context.Context, permissionUUID string) error
+
+	ListTagCategories(ctx context.Context, opts PageOptions) ([]TagCategory, string, annotations.Annotations, error)
+	ListTagValues(ctx context.Context, categoryUUID string, opts PageOptions) ([]TagValue, string, annotations.Annotations, error)
+
+	ListScans(ctx context.Context) ([]Scan, error)
+	ListPolicies(ctx context.Context) ([]Policy, error)
+	GetObjectPermissions(ctx context.Context, objectType string, objectID int) (*ObjectPermissions, error)
+	UpdateObjectPermissions(ctx context.Context, objectType string, objectID int, acls []ACL) error
+	ListCredentials(ctx context.Context, opts PageOptions) ([]ManagedCredential, string, error)
+	GetCredentialDetails(ctx context.Context, credentialUUID string) (*ManagedCredential, error)
+	UpdateCredentialPermissions(ctx context.Context, credentialUUID string, permissions []CredentialPermission) error
+}
+
+var _ TenableAPI = (*TenableVMClient)(nil)
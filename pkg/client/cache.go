@@ -0,0 +1,168 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+// staleAfterWrite lists, for the collections a write can land in, every collection whose reads the write may
+// change. A write anywhere else only drops the reads of its own collection, the first segment of its path.
+var staleAfterWrite = map[string][]string{
+	BaseUsersPath:         {BaseUsersPath, ListGroupsPath, userRolesPath, PermissionsPath},
+	ListGroupsPath:        {ListGroupsPath, PermissionsPath},
+	userRolesPath:         {userRolesPath, BaseUsersPath},
+	RolesPath:             {RolesPath, userRolesPath, BaseUsersPath},
+	objectPermissionsPath: {objectPermissionsPath, ScansPath, PoliciesPath},
+}
+
+const (
+	userRolesPath         = "/access-control/v1/users"
+	objectPermissionsPath = "/permissions"
+)
+
+// responseCache keeps the successful GETs made by one client, configured like the SDK HTTP cache
+// (BATON_HTTP_CACHE_TTL, BATON_HTTP_CACHE_MAX_SIZE, BATON_DISABLE_HTTP_CACHE). The SDK cache is shared by every
+// client in the process and can only be cleared as a whole, while a write here drops only the reads it may have
+// changed.
+type responseCache struct {
+	ttl     time.Duration
+	maxSize int
+
+	mtx        sync.Mutex
+	entries    map[string]cachedResponse
+	size       int
+	generation uint64
+}
+
+type cachedResponse struct {
+	path    string
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+func newResponseCache() *responseCache {
+	config := uhttp.NewCacheConfigFromEnv()
+	c := &responseCache{entries: make(map[string]cachedResponse)}
+	if config.TTL > 0 && config.Backend != uhttp.CacheBackendNoop {
+		c.ttl = time.Duration(config.TTL) * time.Second //nolint:gosec // the SDK caps the ttl
+		c.maxSize = int(config.MaxSize) << 20           //nolint:gosec // the SDK caps the size
+	}
+	return c
+}
+
+// get returns the cached response for the url, if it has not expired.
+func (c *responseCache) get(key string) (*uhttp.WrapperResponse, bool) {
+	if c.ttl == 0 {
+		return nil, false
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		c.remove(key)
+		return nil, false
+	}
+	return &uhttp.WrapperResponse{
+		Header:     entry.header,
+		Status:     http.StatusText(http.StatusOK),
+		StatusCode: http.StatusOK,
+		Body:       entry.body,
+	}, true
+}
+
+// start returns the generation a read has to be stored under, so a response sent before a write it raced with
+// is not cached after the write dropped the reads it changed.
+func (c *responseCache) start() uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.generation
+}
+
+func (c *responseCache) set(key string, path string, generation uint64, resp *uhttp.WrapperResponse) {
+	if c.ttl == 0 {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.remove(key)
+	if c.size+len(resp.Body) > c.maxSize {
+		c.removeExpired()
+		if c.size+len(resp.Body) > c.maxSize {
+			return
+		}
+	}
+	c.entries[key] = cachedResponse{
+		path:    path,
+		header:  resp.Header,
+		body:    resp.Body,
+		expires: time.Now().Add(c.ttl),
+	}
+	c.size += len(resp.Body)
+}
+
+// invalidate drops the cached reads a write to the path may have changed.
+func (c *responseCache) invalidate(path string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.generation++
+	stale := staleCollections(path)
+	for key, entry := range c.entries {
+		for _, collection := range stale {
+			if underPath(entry.path, collection) {
+				c.remove(key)
+				break
+			}
+		}
+	}
+}
+
+func (c *responseCache) remove(key string) {
+	if entry, ok := c.entries[key]; ok {
+		c.size -= len(entry.body)
+		delete(c.entries, key)
+	}
+}
+
+func (c *responseCache) removeExpired() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			c.remove(key)
+		}
+	}
+}
+
+func staleCollections(path string) []string {
+	collection := ""
+	for prefix := range staleAfterWrite {
+		if underPath(path, prefix) && len(prefix) > len(collection) {
+			collection = prefix
+		}
+	}
+	if collection != "" {
+		return staleAfterWrite[collection]
+	}
+
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return []string{"/" + first}
+}
+
+// underPath reports whether path is prefix itself or one of the paths below it.
+func underPath(path string, prefix string) bool {
+	rest, ok := strings.CutPrefix(path, prefix)
+	return ok && (rest == "" || strings.HasPrefix(rest, "/"))
+}
@@ -2,51 +2,147 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
 )
 
 const (
-	BaseURL                 = "https://cloud.tenable.com"
+	DefaultBaseURL          = "https://cloud.tenable.com"
+	FedRAMPBaseURL          = "https://fedcloud.tenable.com"
+	SessionPath             = "/session"
 	BaseUsersPath           = "/users"
 	UserPath                = "/users/%s" // uses user id
+	UserEnabledPath         = "/users/%s/enabled"
+	UserPasswordPath        = "/users/%s/chpasswd"
+	UserKeysPath            = "/users/%s/keys"
+	UserAuthorizationsPath  = "/users/%s/authorizations"
 	ListGroupsPath          = "/groups"
+	GroupPath               = "/groups/%s" // uses group id
 	ListGroupMembersPath    = "/groups/%s/users"
 	UserGroupMembershipPath = "/groups/%s/users/%s"
 	UserRolePath            = "/access-control/v1/users/%s/roles" // uses user uuid, not id
 	RolesPath               = "/access-control/v1/roles"
+	RolePath                = "/access-control/v1/roles/%s" // uses role uuid
 	PermissionsPath         = "/api/v3/access-control/permissions"
+	TagCategoriesPath       = "/tags/categories"
+	TagValuesPath           = "/tags/values"
+	ScansPath               = "/scans"
+	PoliciesPath            = "/policies"
+	ObjectPermissionsPath   = "/permissions/%s/%d" // uses object type and id
+	CredentialsPath         = "/credentials"
+	CredentialPath          = "/credentials/%s" // uses credential uuid
 )
 
+// Regions maps the region aliases accepted in place of a base URL to the Tenable VM API they point at.
+var Regions = map[string]string{
+	"cloud":    DefaultBaseURL,
+	"fedcloud": FedRAMPBaseURL,
+	"fedramp":  FedRAMPBaseURL,
+}
+
 type TenableVMClient struct {
-	httpClient *uhttp.BaseHttpClient
-	accessKey  string
-	secretKey  string
+	// httpClient only builds the requests. send makes them, so reads are kept in cache rather than in the SDK
+	// cache shared by the whole process.
+	httpClient  *uhttp.BaseHttpClient
+	cache       *responseCache
+	baseURL     string
+	basePath    string
+	accessKey   string
+	secretKey   string
+	retryPolicy RetryPolicy
+	limiter     *limiter
 }
 
 type ReqOpt func(reqURL *url.URL)
 
-func NewClient(ctx context.Context, accessKey, secretKey string) (*TenableVMClient, error) {
-	client := &TenableVMClient{
-		httpClient: &uhttp.BaseHttpClient{},
-		accessKey:  accessKey,
-		secretKey:  secretKey,
+// Option configures optional client behaviour.
+type Option func(*TenableVMClient)
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *TenableVMClient) {
+		c.retryPolicy = policy
 	}
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+}
+
+// WithRequestLimits caps the number of requests in flight and the number of requests sent per second,
+// shared by every call made through the client. Zero disables the corresponding limit.
+func WithRequestLimits(maxConcurrent int, requestsPerSecond int) Option {
+	return func(c *TenableVMClient) {
+		c.limiter = newLimiter(maxConcurrent, requestsPerSecond)
+	}
+}
+
+// ResolveBaseURL turns a region alias or an explicit URL into the base URL used for every API call.
+// An empty value resolves to DefaultBaseURL.
+func ResolveBaseURL(baseURL string) (string, error) {
+	baseURL = strings.TrimSpace(baseURL)
+	if baseURL == "" {
+		return DefaultBaseURL, nil
+	}
+	if regionURL, ok := Regions[strings.ToLower(baseURL)]; ok {
+		return regionURL, nil
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base url %q: %w", baseURL, err)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return "", fmt.Errorf("invalid base url %q: scheme must be http or https, or use one of the region aliases %s",
+			baseURL, strings.Join(regionAliases(), ", "))
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid base url %q: missing host", baseURL)
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("invalid base url %q: query and fragment are not allowed", baseURL)
+	}
+
+	return strings.TrimSuffix(parsed.String(), "/"), nil
+}
+
+func NewClient(ctx context.Context, baseURL, accessKey, secretKey string, opts ...Option) (*TenableVMClient, error) {
+	resolvedURL, err := ResolveBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	parsedURL, err := url.Parse(resolvedURL)
 	if err != nil {
 		return nil, err
 	}
-	cli, err := uhttp.NewBaseHttpClientWithContext(context.Background(), httpClient)
+	client := &TenableVMClient{
+		httpClient:  &uhttp.BaseHttpClient{},
+		cache:       newResponseCache(),
+		baseURL:     resolvedURL,
+		basePath:    parsedURL.Path,
+		accessKey:   accessKey,
+		secretKey:   secretKey,
+		retryPolicy: DefaultRetryPolicy(),
+		limiter:     newLimiter(0, 0),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
 	if err != nil {
 		return nil, err
 	}
-	client.httpClient = cli
+	client.httpClient.HttpClient = httpClient
 
 	return client, nil
 }
@@ -57,7 +153,7 @@ func (c *TenableVMClient) GetUsers(ctx context.Context) ([]User, annotations.Ann
 	l := ctxzap.Extract(ctx)
 	var res UsersResponse
 
-	queryUrl, err := url.JoinPath(BaseURL, BaseUsersPath)
+	queryUrl, err := url.JoinPath(c.baseURL, BaseUsersPath)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return nil, nil, err
@@ -74,7 +170,7 @@ func (c *TenableVMClient) GetUsers(ctx context.Context) ([]User, annotations.Ann
 func (c *TenableVMClient) GetUserDetails(ctx context.Context, userId string) (*User, error) {
 	var user User
 
-	queryUrl, err := url.JoinPath(BaseURL, fmt.Sprintf(UserPath, userId))
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserPath, userId))
 	if err != nil {
 		return nil, fmt.Errorf("error creating url: %w", err)
 	}
@@ -86,11 +182,126 @@ func (c *TenableVMClient) GetUserDetails(ctx context.Context, userId string) (*U
 	return &user, nil
 }
 
+// GetSession returns the user owning the API keys the client was built with.
+func (c *TenableVMClient) GetSession(ctx context.Context) (*User, error) {
+	var user User
+
+	queryUrl, err := url.JoinPath(c.baseURL, SessionPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+	_, err = c.getResourcesFromAPI(ctx, queryUrl, &user)
+	if err != nil {
+		return nil, fmt.Errorf("error getting session: %w", err)
+	}
+
+	return &user, nil
+}
+
+// SetUserEnabled enables or disables the user's account without changing anything else about it.
+func (c *TenableVMClient) SetUserEnabled(ctx context.Context, userId string, enabled bool) error {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserEnabledPath, userId))
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, nil, UserEnabledReqBody{Enabled: enabled})
+	if err != nil {
+		return fmt.Errorf("error updating user enabled state: %w", err)
+	}
+
+	return nil
+}
+
+// ChangeUserPassword sets a new password for the user. Administrators changing another user's password do not
+// need to know the current one.
+func (c *TenableVMClient) ChangeUserPassword(ctx context.Context, userId string, password string) error {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserPasswordPath, userId))
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, nil, ChangePasswordReqBody{Password: password})
+	if err != nil {
+		return fmt.Errorf("error changing user password: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateAPIKeys replaces the user's access and secret keys with a new pair. The previous keys stop working
+// immediately.
+func (c *TenableVMClient) GenerateAPIKeys(ctx context.Context, userId string) (*APIKeys, error) {
+	var keys APIKeys
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserKeysPath, userId))
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, &keys, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error generating api keys: %w", err)
+	}
+
+	return &keys, nil
+}
+
+// GetUserAuthorizations returns the login methods the user is allowed to authenticate with.
+func (c *TenableVMClient) GetUserAuthorizations(ctx context.Context, userId string) (*UserAuthorizations, error) {
+	var authorizations UserAuthorizations
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserAuthorizationsPath, userId))
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+	_, err = c.getResourcesFromAPI(ctx, queryUrl, &authorizations)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user authorizations: %w", err)
+	}
+
+	return &authorizations, nil
+}
+
+// UpdateUserAuthorizations replaces the login methods the user is allowed to authenticate with. Every flag is
+// sent, so callers should start from the user's current authorizations.
+func (c *TenableVMClient) UpdateUserAuthorizations(ctx context.Context, userId string, authorizations UserAuthorizations) error {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserAuthorizationsPath, userId))
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, nil, UserAuthorizationsReqBody{
+		APIPermitted:      authorizations.APIPermitted,
+		PasswordPermitted: authorizations.PasswordPermitted,
+		SAMLPermitted:     authorizations.SAMLPermitted,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating user authorizations: %w", err)
+	}
+
+	return nil
+}
+
+func (c *TenableVMClient) DeleteUser(ctx context.Context, userId string) error {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserPath, userId))
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodDelete, queryUrl, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+
+	return nil
+}
+
 func (c *TenableVMClient) GetRoles(ctx context.Context) ([]*RoleDetails, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	var res []*RoleDetails
 
-	queryUrl, err := url.JoinPath(BaseURL, RolesPath)
+	queryUrl, err := url.JoinPath(c.baseURL, RolesPath)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return nil, nil, err
@@ -105,10 +316,58 @@ func (c *TenableVMClient) GetRoles(ctx context.Context) ([]*RoleDetails, annotat
 	return res, annos, nil
 }
 
+// CreateRole adds a custom RBAC role.
+func (c *TenableVMClient) CreateRole(ctx context.Context, role RoleReqBody) (*RoleDetails, error) {
+	var created RoleDetails
+
+	queryUrl, err := url.JoinPath(c.baseURL, RolesPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodPost, queryUrl, &created, role)
+	if err != nil {
+		return nil, fmt.Errorf("error creating role: %w", err)
+	}
+
+	return &created, nil
+}
+
+// UpdateRole replaces the name, description and permission strings of a custom RBAC role.
+func (c *TenableVMClient) UpdateRole(ctx context.Context, roleUUID string, role RoleReqBody) (*RoleDetails, error) {
+	var updated RoleDetails
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(RolePath, roleUUID))
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, &updated, role)
+	if err != nil {
+		return nil, fmt.Errorf("error updating role: %w", err)
+	}
+
+	return &updated, nil
+}
+
+func (c *TenableVMClient) DeleteRole(ctx context.Context, roleUUID string) error {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(RolePath, roleUUID))
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodDelete, queryUrl, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error deleting role: %w", err)
+	}
+
+	return nil
+}
+
 func (c *TenableVMClient) GetUserRoles(ctx context.Context, userUUID string) (*UserRole, error) {
 	var userRoles UserRole
 
-	queryUrl, err := url.JoinPath(BaseURL, fmt.Sprintf(UserRolePath, userUUID))
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserRolePath, userUUID))
 	if err != nil {
 		return nil, fmt.Errorf("error creating url: %w", err)
 	}
@@ -123,7 +382,7 @@ func (c *TenableVMClient) GetUserRoles(ctx context.Context, userUUID string) (*U
 func (c *TenableVMClient) UpdateUser(ctx context.Context, userId string, body UserUpdateReqBody) (*User, error) {
 	var user User
 
-	queryUrl, err := url.JoinPath(BaseURL, fmt.Sprintf(UserPath, userId))
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserPath, userId))
 	if err != nil {
 		return nil, fmt.Errorf("error creating url: %w", err)
 	}
@@ -135,15 +394,16 @@ func (c *TenableVMClient) UpdateUser(ctx context.Context, userId string, body Us
 	return &user, nil
 }
 
-func (c *TenableVMClient) UpdateUserRoles(ctx context.Context, userUUID string, roleUUID string) (*UserRole, error) {
+// UpdateUserRoles replaces the full set of RBAC roles assigned to the user with roleUUIDs.
+func (c *TenableVMClient) UpdateUserRoles(ctx context.Context, userUUID string, roleUUIDs []string) (*UserRole, error) {
 	var userRoles UserRole
 
-	queryUrl, err := url.JoinPath(BaseURL, fmt.Sprintf(UserRolePath, userUUID))
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(UserRolePath, userUUID))
 	if err != nil {
 		return nil, fmt.Errorf("error creating url: %w", err)
 	}
-	body := UserRoleReqBody{RolesUUIDs: []string{roleUUID}}
-	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, userRoles, body)
+	body := UserRoleReqBody{RolesUUIDs: roleUUIDs}
+	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, &userRoles, body)
 	if err != nil {
 		return nil, fmt.Errorf("error updating user role: %w", err)
 	}
@@ -154,7 +414,7 @@ func (c *TenableVMClient) UpdateUserRoles(ctx context.Context, userUUID string,
 func (c *TenableVMClient) CreateUser(ctx context.Context, newUser NewUser) (*User, error) {
 	var user User
 
-	queryUrl, err := url.JoinPath(BaseURL, BaseUsersPath)
+	queryUrl, err := url.JoinPath(c.baseURL, BaseUsersPath)
 	if err != nil {
 		return nil, fmt.Errorf("error creating url: %w", err)
 	}
@@ -171,7 +431,7 @@ func (c *TenableVMClient) GetGroups(ctx context.Context) ([]Group, annotations.A
 	l := ctxzap.Extract(ctx)
 	var res GroupsResponse
 
-	queryUrl, err := url.JoinPath(BaseURL, ListGroupsPath)
+	queryUrl, err := url.JoinPath(c.baseURL, ListGroupsPath)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return nil, nil, err
@@ -186,13 +446,43 @@ func (c *TenableVMClient) GetGroups(ctx context.Context) ([]Group, annotations.A
 	return res.Groups, annotations, nil
 }
 
+func (c *TenableVMClient) CreateGroup(ctx context.Context, name string) (*Group, error) {
+	var group Group
+
+	queryUrl, err := url.JoinPath(c.baseURL, ListGroupsPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodPost, queryUrl, &group, GroupReqBody{Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("error creating group: %w", err)
+	}
+
+	return &group, nil
+}
+
+func (c *TenableVMClient) DeleteGroup(ctx context.Context, groupId string) error {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(GroupPath, groupId))
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodDelete, queryUrl, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error deleting group: %w", err)
+	}
+
+	return nil
+}
+
 func (c *TenableVMClient) GetGroupMembers(ctx context.Context, groupId string) ([]User, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	var res UsersResponse
 
 	path := fmt.Sprintf(ListGroupMembersPath, groupId)
 
-	queryUrl, err := url.JoinPath(BaseURL, path)
+	queryUrl, err := url.JoinPath(c.baseURL, path)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return nil, nil, err
@@ -211,7 +501,7 @@ func (c *TenableVMClient) DeleteUserGroupMembership(ctx context.Context, groupId
 	l := ctxzap.Extract(ctx)
 	path := fmt.Sprintf(UserGroupMembershipPath, groupId, userId)
 
-	queryUrl, err := url.JoinPath(BaseURL, path)
+	queryUrl, err := url.JoinPath(c.baseURL, path)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return err
@@ -229,7 +519,7 @@ func (c *TenableVMClient) DeleteUserGroupMembership(ctx context.Context, groupId
 func (c *TenableVMClient) CreateUserGroupMembership(ctx context.Context, groupId string, userId string, add bool) error {
 	l := ctxzap.Extract(ctx)
 	path := fmt.Sprintf(UserGroupMembershipPath, groupId, userId)
-	queryUrl, err := url.JoinPath(BaseURL, path)
+	queryUrl, err := url.JoinPath(c.baseURL, path)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return err
@@ -243,30 +533,80 @@ func (c *TenableVMClient) CreateUserGroupMembership(ctx context.Context, groupId
 	return nil
 }
 
-func (c *TenableVMClient) ListPermissions(ctx context.Context) ([]Permission, annotations.Annotations, error) {
+// ListPermissions returns one page of access control permissions, along with the token of the next page.
+func (c *TenableVMClient) ListPermissions(ctx context.Context, opts PageOptions) ([]Permission, string, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	var res PermissionsList
 
-	queryUrl, err := url.JoinPath(BaseURL, PermissionsPath)
+	queryUrl, err := url.JoinPath(c.baseURL, PermissionsPath)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
-		return nil, nil, err
+		return nil, "", nil, err
 	}
 
-	annos, err := c.getResourcesFromAPI(ctx, queryUrl, &res)
+	annos, err := c.getResourcesFromAPI(ctx, queryUrl, &res, withPageOptions(opts))
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, annos, err
+		return nil, "", annos, err
+	}
+
+	nextToken, err := nextPageToken(res.Pagination, opts, len(res.Permissions))
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return res.Permissions, nextToken, annos, nil
+}
+
+// ListTagCategories returns one page of tag categories, along with the token of the next page.
+func (c *TenableVMClient) ListTagCategories(ctx context.Context, opts PageOptions) ([]TagCategory, string, annotations.Annotations, error) {
+	var res TagCategoriesList
+
+	queryUrl, err := url.JoinPath(c.baseURL, TagCategoriesPath)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error creating url: %w", err)
+	}
+
+	annos, err := c.getResourcesFromAPI(ctx, queryUrl, &res, withPageOptions(opts))
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error listing tag categories: %w", err)
+	}
+
+	nextToken, err := nextPageToken(res.Pagination, opts, len(res.Categories))
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return res.Categories, nextToken, annos, nil
+}
+
+// ListTagValues returns one page of the values of the tag category, along with the token of the next page.
+func (c *TenableVMClient) ListTagValues(ctx context.Context, categoryUUID string, opts PageOptions) ([]TagValue, string, annotations.Annotations, error) {
+	var res TagValuesList
+
+	queryUrl, err := url.JoinPath(c.baseURL, TagValuesPath)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error creating url: %w", err)
 	}
 
-	return res.Permissions, annos, nil
+	annos, err := c.getResourcesFromAPI(ctx, queryUrl, &res, withPageOptions(opts), withTagCategory(categoryUUID))
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("error listing tag values: %w", err)
+	}
+
+	nextToken, err := nextPageToken(res.Pagination, opts, len(res.Values))
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return res.Values, nextToken, annos, nil
 }
 
 func (c *TenableVMClient) GetPermissionDetails(ctx context.Context, uuid string) (*Permission, error) {
 	l := ctxzap.Extract(ctx)
 	var res Permission
 
-	queryUrl, err := url.JoinPath(BaseURL, PermissionsPath, uuid)
+	queryUrl, err := url.JoinPath(c.baseURL, PermissionsPath, uuid)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return nil, err
@@ -290,7 +630,7 @@ func (c *TenableVMClient) UpdatePermission(ctx context.Context, updatedPermissio
 		Objects:  parseTagNames(updatedPermission.Objects),
 		Subjects: updatedPermission.Subjects,
 	}
-	queryUrl, err := url.JoinPath(BaseURL, PermissionsPath, updatedPermission.UUID.String())
+	queryUrl, err := url.JoinPath(c.baseURL, PermissionsPath, updatedPermission.UUID.String())
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return err
@@ -305,6 +645,58 @@ func (c *TenableVMClient) UpdatePermission(ctx context.Context, updatedPermissio
 	return nil
 }
 
+// CreatePermission adds an access control permission and returns it with the uuid Tenable assigned.
+func (c *TenableVMClient) CreatePermission(ctx context.Context, permission *Permission) (*Permission, error) {
+	var res PermissionCreateResponse
+
+	permissionBody := PermissionUpdateBody{
+		Name:     permission.Name,
+		Actions:  permission.Actions,
+		Objects:  parseTagNames(slices.Clone(permission.Objects)),
+		Subjects: permission.Subjects,
+	}
+	queryUrl, err := url.JoinPath(c.baseURL, PermissionsPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodPost, queryUrl, &res, permissionBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating permission: %w", err)
+	}
+
+	created := *permission
+	created.UUID = res.UUID
+	return &created, nil
+}
+
+func (c *TenableVMClient) DeletePermission(ctx context.Context, permissionUUID string) error {
+	queryUrl, err := url.JoinPath(c.baseURL, PermissionsPath, permissionUUID)
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+
+	_, _, err = c.doRequest(ctx, http.MethodDelete, queryUrl, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error deleting permission: %w", err)
+	}
+
+	return nil
+}
+
+type uncachedKey struct{}
+
+// WithoutCache returns a context whose reads skip the HTTP cache. Reads a write is computed from must use it: a
+// cached response can be up to an hour old, and writing it back would undo the changes made since.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, uncachedKey{}, true)
+}
+
+func uncached(ctx context.Context) bool {
+	v, _ := ctx.Value(uncachedKey{}).(bool)
+	return v
+}
+
 func (c *TenableVMClient) getResourcesFromAPI(
 	ctx context.Context,
 	urlAddress string,
@@ -327,10 +719,7 @@ func (c *TenableVMClient) doRequest(
 	body interface{},
 	reqOpt ...ReqOpt,
 ) (http.Header, annotations.Annotations, error) {
-	var (
-		resp *http.Response
-		err  error
-	)
+	l := ctxzap.Extract(ctx)
 
 	urlAddress, err := url.Parse(endpointUrl)
 	if err != nil {
@@ -341,6 +730,47 @@ func (c *TenableVMClient) doRequest(
 		o(urlAddress)
 	}
 
+	for attempt := 0; ; attempt++ {
+		header, annos, err := c.doRequestOnce(ctx, method, urlAddress, res, body)
+		if err == nil {
+			return header, annos, nil
+		}
+
+		delay, retry := c.retryPolicy.nextDelay(method, attempt, err)
+		if !retry {
+			return nil, nil, err
+		}
+		l.Debug("retrying request",
+			zap.String("method", method),
+			zap.String("path", urlAddress.Path),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *TenableVMClient) doRequestOnce(
+	ctx context.Context,
+	method string,
+	urlAddress *url.URL,
+	res interface{},
+	body interface{},
+) (http.Header, annotations.Annotations, error) {
+	release, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
 	apiKeyHeader := fmt.Sprintf("accessKey=%s; secretKey=%s", c.accessKey, c.secretKey)
 	requestOptions := []uhttp.RequestOption{
 		uhttp.WithAcceptJSONHeader(),
@@ -360,27 +790,35 @@ func (c *TenableVMClient) doRequest(
 	if err != nil {
 		return nil, nil, err
 	}
-	var ratelimitData v2.RateLimitDescription
-	var doOptions []uhttp.DoOption
-	doOptions = append(doOptions, uhttp.WithRatelimitData(&ratelimitData))
-	switch method {
-	case http.MethodGet, http.MethodPut, http.MethodPost:
-
-		if res != nil {
-			doOptions = append(doOptions, uhttp.WithResponse(&res))
-		}
-		resp, err = c.httpClient.Do(req, doOptions...)
+	var (
+		ratelimitData v2.RateLimitDescription
+		apiErr        *APIError
+	)
+	doOptions := []uhttp.DoOption{
+		uhttp.WithRatelimitData(&ratelimitData),
+		withAPIError(&apiErr),
+	}
+	if res != nil && method != http.MethodDelete {
+		doOptions = append(doOptions, uhttp.WithResponse(&res))
+	}
 
-		if resp != nil {
-			defer resp.Body.Close()
-		}
-	case http.MethodDelete:
-		resp, err = c.httpClient.Do(req)
-		if resp != nil {
-			defer resp.Body.Close()
+	resp, err := c.fetch(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	var optErrs []error
+	for _, option := range doOptions {
+		if err := option(resp); err != nil {
+			optErrs = append(optErrs, err)
 		}
 	}
-	if err != nil {
+	if apiErr != nil {
+		apiErr.Method = method
+		apiErr.Path = urlAddress.Path
+		apiErr.RateLimit = &ratelimitData
+		return nil, nil, apiErr
+	}
+	if err := errors.Join(optErrs...); err != nil {
 		return nil, nil, err
 	}
 	annotation := annotations.Annotations{}
@@ -388,3 +826,62 @@ func (c *TenableVMClient) doRequest(
 
 	return resp.Header, annotation, nil
 }
+
+// fetch sends the request, answering GETs from the client's response cache unless the context asks for a fresh
+// read. A write drops the cached reads it may have changed, even when it failed, since it may have been applied.
+func (c *TenableVMClient) fetch(ctx context.Context, req *http.Request) (*uhttp.WrapperResponse, error) {
+	key := req.URL.String()
+	path := strings.TrimPrefix(req.URL.Path, c.basePath)
+	if req.Method != http.MethodGet {
+		defer c.cache.invalidate(path)
+		return c.send(req)
+	}
+
+	if !uncached(ctx) {
+		if resp, ok := c.cache.get(key); ok {
+			ctxzap.Extract(ctx).Debug("http cache hit", zap.String("url", key))
+			return resp, nil
+		}
+	}
+	generation := c.cache.start()
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		c.cache.set(key, path, generation, resp)
+	}
+	return resp, nil
+}
+
+// send makes the request and reads the whole response. Transport failures worth retrying come back as the gRPC
+// statuses uhttp uses for them.
+func (c *TenableVMClient) send(req *http.Request) (*uhttp.WrapperResponse, error) {
+	resp, err := c.httpClient.HttpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && urlErr.Timeout() || errors.Is(err, context.DeadlineExceeded) {
+			return nil, uhttp.WrapErrors(codes.DeadlineExceeded, "request timeout", err)
+		}
+		if errors.Is(err, syscall.ECONNRESET) {
+			return nil, uhttp.WrapErrors(codes.Unavailable, "connection reset", err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+			return nil, uhttp.WrapErrors(codes.Unavailable, "error reading response", err)
+		}
+		return nil, err
+	}
+
+	return &uhttp.WrapperResponse{
+		Header:     resp.Header,
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Body:       body,
+	}, nil
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"net/url"
+	"slices"
 	"strings"
 )
 
@@ -9,6 +10,11 @@ func withRoles() ReqOpt {
 	return withQueryParam("withRoles", "true")
 }
 
+// withTagCategory restricts a tag values listing to one category.
+func withTagCategory(categoryUUID string) ReqOpt {
+	return withQueryParam("f", "category_uuid:eq:"+categoryUUID)
+}
+
 func withQueryParam(key string, value string) ReqOpt {
 	return func(reqURL *url.URL) {
 		q := reqURL.Query()
@@ -25,3 +31,12 @@ func parseTagNames(objs []TenableObject) []TenableObject {
 	}
 	return objs
 }
+
+func regionAliases() []string {
+	aliases := make([]string, 0, len(Regions))
+	for alias := range Regions {
+		aliases = append(aliases, alias)
+	}
+	slices.Sort(aliases)
+	return aliases
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUserRoles(t *testing.T) {
+	var got UserRoleReqBody
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "/access-control/v1/users/user-uuid/roles", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(UserRole{ContainerUUID: "container", UserUUID: "user-uuid", RolesUUID: got.RolesUUIDs})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cli, err := NewClient(ctx, server.URL, "access", "secret")
+	require.NoError(t, err)
+
+	userRoles, err := cli.UpdateUserRoles(ctx, "user-uuid", []string{"role-a", "role-b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"role-a", "role-b"}, got.RolesUUIDs)
+	require.Equal(t, &UserRole{ContainerUUID: "container", UserUUID: "user-uuid", RolesUUID: []string{"role-a", "role-b"}}, userRoles)
+}
+
+func TestHTTPCache(t *testing.T) {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "false")
+	var roles []string
+	gets := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut:
+			var body UserRoleReqBody
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			roles = body.RolesUUIDs
+		case r.URL.Path == ListGroupsPath:
+			gets[r.URL.Path]++
+			_ = json.NewEncoder(w).Encode(GroupsResponse{})
+			return
+		default:
+			gets[r.URL.Path]++
+		}
+		_ = json.NewEncoder(w).Encode(UserRole{UserUUID: "user-uuid", RolesUUID: roles})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cli, err := NewClient(ctx, server.URL, "access", "secret")
+	require.NoError(t, err)
+	rolesPath := fmt.Sprintf(UserRolePath, "user-uuid")
+
+	_, err = cli.GetUserRoles(ctx, "user-uuid")
+	require.NoError(t, err)
+	_, err = cli.GetUserRoles(ctx, "user-uuid")
+	require.NoError(t, err)
+	require.Equal(t, 1, gets[rolesPath], "the second read is cached")
+
+	_, err = cli.GetUserRoles(WithoutCache(ctx), "user-uuid")
+	require.NoError(t, err)
+	require.Equal(t, 2, gets[rolesPath], "uncached reads reach the API")
+
+	_, _, err = cli.GetGroups(ctx)
+	require.NoError(t, err)
+	_, err = cli.UpdateUserRoles(ctx, "user-uuid", []string{"role-a"})
+	require.NoError(t, err)
+	userRoles, err := cli.GetUserRoles(ctx, "user-uuid")
+	require.NoError(t, err)
+	require.Equal(t, []string{"role-a"}, userRoles.RolesUUID, "writes drop the cached reads they change")
+	require.Equal(t, 3, gets[rolesPath])
+
+	_, _, err = cli.GetGroups(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, gets[ListGroupsPath], "writes keep the cached reads they do not change")
+}
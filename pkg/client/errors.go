@@ -0,0 +1,127 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sentinel errors for the failure classes the connector reacts to. An *APIError unwraps to one of them,
+// so callers can use errors.Is(err, client.ErrNotFound) no matter how many times the error was wrapped.
+var (
+	ErrValidationFailed = errors.New("tenable: validation failed")
+	ErrUnauthorized     = errors.New("tenable: unauthorized")
+	ErrForbidden        = errors.New("tenable: forbidden")
+	ErrNotFound         = errors.New("tenable: not found")
+	ErrConflict         = errors.New("tenable: conflict")
+	ErrTimeout          = errors.New("tenable: request timeout")
+	ErrRateLimited      = errors.New("tenable: rate limited")
+	ErrServerError      = errors.New("tenable: server error")
+	ErrUnexpectedStatus = errors.New("tenable: unexpected status")
+)
+
+// APIError is the JSON error body returned by the Tenable VM API, e.g.
+// {"statusCode": 404, "error": "Not Found", "message": "User not found"}.
+type APIError struct {
+	StatusCode int    `json:"statusCode,omitempty"`
+	ErrorCode  string `json:"error,omitempty"`
+	Message    string `json:"message,omitempty"`
+
+	Method     string                   `json:"-"`
+	Path       string                   `json:"-"`
+	RetryAfter time.Duration            `json:"-"`
+	RateLimit  *v2.RateLimitDescription `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.ErrorCode
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("tenable: %s %s failed with status %d: %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+// Unwrap returns the sentinel error matching the HTTP status of the response.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest, e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrValidationFailed
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusRequestTimeout:
+		return ErrTimeout
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServerError
+	default:
+		return ErrUnexpectedStatus
+	}
+}
+
+// GRPCStatus maps the error to a gRPC status, so Baton retries transient failures (Unavailable,
+// DeadlineExceeded) and gives up on permanent ones.
+func (e *APIError) GRPCStatus() *status.Status {
+	var code codes.Code
+	switch e.Unwrap() {
+	case ErrValidationFailed:
+		code = codes.InvalidArgument
+	case ErrUnauthorized:
+		code = codes.Unauthenticated
+	case ErrForbidden:
+		code = codes.PermissionDenied
+	case ErrNotFound:
+		code = codes.NotFound
+	case ErrConflict:
+		code = codes.AlreadyExists
+	case ErrTimeout:
+		code = codes.DeadlineExceeded
+	case ErrRateLimited, ErrServerError:
+		code = codes.Unavailable
+	default:
+		code = codes.Unknown
+	}
+
+	st := status.New(code, e.Error())
+	if e.RateLimit != nil {
+		if withDetails, err := st.WithDetails(e.RateLimit); err == nil {
+			st = withDetails
+		}
+	}
+	return st
+}
+
+// withAPIError decodes the body of a non-2xx response into an *APIError.
+func withAPIError(apiErr **APIError) uhttp.DoOption {
+	return func(resp *uhttp.WrapperResponse) error {
+		if resp.StatusCode < http.StatusMultipleChoices {
+			return nil
+		}
+
+		parsed := &APIError{}
+		if err := json.Unmarshal(resp.Body, parsed); err != nil {
+			// Not every error comes back as JSON (e.g. load balancer errors), fall back to the status text.
+			parsed = &APIError{}
+		}
+		parsed.StatusCode = resp.StatusCode
+		parsed.RetryAfter = parseRetryAfter(resp.Header)
+		*apiErr = parsed
+		return nil
+	}
+}
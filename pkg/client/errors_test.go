@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+		body       string
+		sentinel   error
+		code       codes.Code
+	}{
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			body:       `{"statusCode":404,"error":"Not Found","message":"User not found"}`,
+			sentinel:   ErrNotFound,
+			code:       codes.NotFound,
+		},
+		{
+			name:       "forbidden",
+			statusCode: http.StatusForbidden,
+			body:       `{"statusCode":403,"error":"Forbidden","message":"You do not have permission"}`,
+			sentinel:   ErrForbidden,
+			code:       codes.PermissionDenied,
+		},
+		{
+			name:       "conflict",
+			statusCode: http.StatusConflict,
+			body:       `{"statusCode":409,"error":"Conflict","message":"Duplicate username"}`,
+			sentinel:   ErrConflict,
+			code:       codes.AlreadyExists,
+		},
+		{
+			name:       "validation failed",
+			statusCode: http.StatusBadRequest,
+			body:       `{"statusCode":400,"error":"Bad Request","message":"Invalid password"}`,
+			sentinel:   ErrValidationFailed,
+			code:       codes.InvalidArgument,
+		},
+		{
+			name:       "rate limited",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"statusCode":429,"error":"Too Many Requests","message":"Slow down"}`,
+			sentinel:   ErrRateLimited,
+			code:       codes.Unavailable,
+		},
+		{
+			name:       "server error without json body",
+			statusCode: http.StatusBadGateway,
+			body:       `<html>bad gateway</html>`,
+			sentinel:   ErrServerError,
+			code:       codes.Unavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			cli, err := NewClient(ctx, server.URL, "access", "secret", WithRetryPolicy(RetryPolicy{}))
+			require.NoError(t, err)
+
+			_, err = cli.GetUserDetails(ctx, "42")
+			require.Error(t, err)
+			require.ErrorIs(t, err, tc.sentinel)
+			require.Equal(t, tc.code, status.Code(err))
+
+			var apiErr *APIError
+			require.True(t, errors.As(err, &apiErr))
+			require.Equal(t, tc.statusCode, apiErr.StatusCode)
+			require.Equal(t, "/users/42", apiErr.Path)
+		})
+	}
+}
@@ -0,0 +1,726 @@
+// Package faketenable is an in-process fake of the Tenable VM API, used to run the connector end to end
+// without network access. It implements the endpoints used to sync and to manage users, groups and roles, and
+// keeps everything in memory.
+package faketenable
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/google/uuid"
+)
+
+const (
+	AccessKey = "fake-access-key"
+	SecretKey = "fake-secret-key"
+)
+
+// State is the data served by a Server. Group members are keyed by group id and hold user ids, user roles
+// are keyed by user uuid and hold role uuids, matching the identifiers used by the real API. SessionUserID is
+// the id of the user owning the API keys.
+type State struct {
+	SessionUserID int
+	Users         []client.User
+	Groups        []client.Group
+	GroupMembers  map[int][]int
+	Roles         []client.RoleDetails
+	UserRoles     map[string][]string
+	Permissions   []client.Permission
+	TagCategories []client.TagCategory
+	TagValues     []client.TagValue
+	// Passwords holds the passwords set through the API, keyed by user ID.
+	Passwords map[string]string
+	// Authorizations holds the login methods of each user, keyed by user ID. Users without an entry may not
+	// log in at all.
+	Authorizations map[int]client.UserAuthorizations
+}
+
+// Server is an httptest.Server answering like the Tenable VM API. Requests must carry the X-ApiKeys header
+// built from AccessKey and SecretKey.
+type Server struct {
+	*httptest.Server
+
+	mtx   sync.Mutex
+	state State
+}
+
+// New starts a Server seeded with state. Callers must Close it.
+func New(state State) *Server {
+	if state.GroupMembers == nil {
+		state.GroupMembers = make(map[int][]int)
+	}
+	if state.UserRoles == nil {
+		state.UserRoles = make(map[string][]string)
+	}
+	if state.Passwords == nil {
+		state.Passwords = make(map[string]string)
+	}
+	if state.Authorizations == nil {
+		state.Authorizations = make(map[int]client.UserAuthorizations)
+	}
+	s := &Server{state: state}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+client.SessionPath, s.getSession)
+	mux.HandleFunc("GET "+client.BaseUsersPath, s.listUsers)
+	mux.HandleFunc("POST "+client.BaseUsersPath, s.createUser)
+	mux.HandleFunc("GET /users/{id}", s.getUser)
+	mux.HandleFunc("PUT /users/{id}", s.updateUser)
+	mux.HandleFunc("DELETE /users/{id}", s.deleteUser)
+	mux.HandleFunc("PUT /users/{id}/enabled", s.setUserEnabled)
+	mux.HandleFunc("PUT /users/{id}/chpasswd", s.changeUserPassword)
+	mux.HandleFunc("PUT /users/{id}/keys", s.generateAPIKeys)
+	mux.HandleFunc("GET /users/{id}/authorizations", s.getUserAuthorizations)
+	mux.HandleFunc("PUT /users/{id}/authorizations", s.updateUserAuthorizations)
+	mux.HandleFunc("GET "+client.ListGroupsPath, s.listGroups)
+	mux.HandleFunc("POST "+client.ListGroupsPath, s.createGroup)
+	mux.HandleFunc("DELETE /groups/{id}", s.deleteGroup)
+	mux.HandleFunc("GET /groups/{id}/users", s.listGroupMembers)
+	mux.HandleFunc("POST /groups/{id}/users/{user_id}", s.addGroupMember)
+	mux.HandleFunc("DELETE /groups/{id}/users/{user_id}", s.removeGroupMember)
+	mux.HandleFunc("GET "+client.RolesPath, s.listRoles)
+	mux.HandleFunc("POST "+client.RolesPath, s.createRole)
+	mux.HandleFunc("PUT "+client.RolesPath+"/{uuid}", s.updateRole)
+	mux.HandleFunc("DELETE "+client.RolesPath+"/{uuid}", s.deleteRole)
+	mux.HandleFunc("GET /access-control/v1/users/{uuid}/roles", s.getUserRoles)
+	mux.HandleFunc("PUT /access-control/v1/users/{uuid}/roles", s.updateUserRoles)
+	mux.HandleFunc("GET "+client.PermissionsPath, s.listPermissions)
+	mux.HandleFunc("GET "+client.PermissionsPath+"/{uuid}", s.getPermission)
+	mux.HandleFunc("PUT "+client.PermissionsPath+"/{uuid}", s.updatePermission)
+	mux.HandleFunc("POST "+client.PermissionsPath, s.createPermission)
+	mux.HandleFunc("GET "+client.TagCategoriesPath, s.listTagCategories)
+	mux.HandleFunc("GET "+client.TagValuesPath, s.listTagValues)
+	mux.HandleFunc("DELETE "+client.PermissionsPath+"/{uuid}", s.deletePermission)
+
+	s.Server = httptest.NewServer(s.authenticate(mux))
+	return s
+}
+
+// State returns a copy of the current state, for assertions after provisioning calls.
+func (s *Server) State() State {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	state := State{
+		SessionUserID:  s.state.SessionUserID,
+		Users:          slices.Clone(s.state.Users),
+		Groups:         slices.Clone(s.state.Groups),
+		GroupMembers:   make(map[int][]int, len(s.state.GroupMembers)),
+		Roles:          slices.Clone(s.state.Roles),
+		UserRoles:      make(map[string][]string, len(s.state.UserRoles)),
+		Permissions:    slices.Clone(s.state.Permissions),
+		TagCategories:  slices.Clone(s.state.TagCategories),
+		TagValues:      slices.Clone(s.state.TagValues),
+		Passwords:      maps.Clone(s.state.Passwords),
+		Authorizations: maps.Clone(s.state.Authorizations),
+	}
+	for id, members := range s.state.GroupMembers {
+		state.GroupMembers[id] = slices.Clone(members)
+	}
+	for userUUID, roles := range s.state.UserRoles {
+		state.UserRoles[userUUID] = slices.Clone(roles)
+	}
+	return state
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	want := fmt.Sprintf("accessKey=%s; secretKey=%s", AccessKey, SecretKey)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ApiKeys") != want {
+			writeError(w, http.StatusUnauthorized, "Invalid Credentials")
+			return
+		}
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) getSession(w http.ResponseWriter, _ *http.Request) {
+	i := s.userIndex(s.state.SessionUserID)
+	if i < 0 {
+		writeError(w, http.StatusUnauthorized, "Invalid Credentials")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.state.Users[i])
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	users := make([]client.User, 0, len(s.state.Users))
+	for _, user := range s.state.Users {
+		users = append(users, s.withRoles(r, user))
+	}
+	writeJSON(w, http.StatusOK, client.UsersResponse{Users: users})
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var body client.NewUser
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Username == "" || body.Permissions == 0 {
+		writeError(w, http.StatusBadRequest, "username and permissions are required")
+		return
+	}
+	if slices.ContainsFunc(s.state.Users, func(user client.User) bool { return user.Username == body.Username }) {
+		writeError(w, http.StatusConflict, "Duplicate username")
+		return
+	}
+
+	id := 1
+	for _, user := range s.state.Users {
+		id = max(id, user.ID+1)
+	}
+	user := client.User{
+		ID:          id,
+		UUID:        uuid.NewString(),
+		Username:    body.Username,
+		Email:       body.Email,
+		Name:        body.Name,
+		Permissions: body.Permissions,
+		Enabled:     true,
+	}
+	s.state.Users = append(s.state.Users, user)
+	if body.Password != "" {
+		s.state.Passwords[strconv.Itoa(id)] = body.Password
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findUser(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.withRoles(r, s.state.Users[i]))
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findUser(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	var body client.UserUpdateReqBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	user := &s.state.Users[i]
+	if body.Name != "" {
+		user.Name = body.Name
+	}
+	if body.Email != "" {
+		user.Email = body.Email
+	}
+	if body.Permissions != 0 {
+		user.Permissions = body.Permissions
+	}
+	if body.Enabled {
+		user.Enabled = true
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) setUserEnabled(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findUser(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	var body client.UserEnabledReqBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s.state.Users[i].Enabled = body.Enabled
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) changeUserPassword(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.findUser(w, r.PathValue("id")); !ok {
+		return
+	}
+	var body client.ChangePasswordReqBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Password == "" {
+		writeError(w, http.StatusBadRequest, "password is required")
+		return
+	}
+	s.state.Passwords[r.PathValue("id")] = body.Password
+	w.WriteHeader(http.StatusOK)
+}
+
+// generateAPIKeys hands out a fresh key pair. The fake keeps accepting AccessKey and SecretKey afterwards.
+func (s *Server) generateAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.findUser(w, r.PathValue("id")); !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, client.APIKeys{
+		AccessKey: strings.ReplaceAll(uuid.NewString(), "-", ""),
+		SecretKey: strings.ReplaceAll(uuid.NewString(), "-", ""),
+	})
+}
+
+func (s *Server) getUserAuthorizations(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findUser(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	user := s.state.Users[i]
+	authorizations := s.state.Authorizations[user.ID]
+	authorizations.UserUUID = user.UUID
+	writeJSON(w, http.StatusOK, authorizations)
+}
+
+func (s *Server) updateUserAuthorizations(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findUser(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	var body client.UserAuthorizationsReqBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	user := s.state.Users[i]
+	s.state.Authorizations[user.ID] = client.UserAuthorizations{
+		UserUUID:          user.UUID,
+		APIPermitted:      body.APIPermitted,
+		PasswordPermitted: body.PasswordPermitted,
+		SAMLPermitted:     body.SAMLPermitted,
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findUser(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	user := s.state.Users[i]
+	s.state.Users = slices.Delete(s.state.Users, i, i+1)
+	delete(s.state.UserRoles, user.UUID)
+	for groupID, members := range s.state.GroupMembers {
+		s.state.GroupMembers[groupID] = slices.DeleteFunc(members, func(id int) bool { return id == user.ID })
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listGroups(w http.ResponseWriter, _ *http.Request) {
+	groups := make([]client.Group, 0, len(s.state.Groups))
+	for _, group := range s.state.Groups {
+		group.UsersCount = len(s.state.GroupMembers[group.ID])
+		groups = append(groups, group)
+	}
+	writeJSON(w, http.StatusOK, client.GroupsResponse{Groups: groups})
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	var body client.GroupReqBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusBadRequest, "Group name is required")
+		return
+	}
+	if slices.ContainsFunc(s.state.Groups, func(group client.Group) bool { return group.Name == body.Name }) {
+		writeError(w, http.StatusConflict, "Duplicate group name")
+		return
+	}
+
+	id := 1
+	for _, group := range s.state.Groups {
+		id = max(id, group.ID+1)
+	}
+	group := client.Group{ID: id, UUID: uuid.NewString(), Name: body.Name}
+	s.state.Groups = append(s.state.Groups, group)
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (s *Server) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	groupID, ok := s.findGroup(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	s.state.Groups = slices.DeleteFunc(s.state.Groups, func(group client.Group) bool {
+		return group.ID == groupID
+	})
+	delete(s.state.GroupMembers, groupID)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listGroupMembers(w http.ResponseWriter, r *http.Request) {
+	groupID, ok := s.findGroup(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	users := []client.User{}
+	for _, userID := range s.state.GroupMembers[groupID] {
+		if i := s.userIndex(userID); i >= 0 {
+			users = append(users, s.state.Users[i])
+		}
+	}
+	writeJSON(w, http.StatusOK, client.UsersResponse{Users: users})
+}
+
+func (s *Server) addGroupMember(w http.ResponseWriter, r *http.Request) {
+	groupID, ok := s.findGroup(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	i, ok := s.findUser(w, r.PathValue("user_id"))
+	if !ok {
+		return
+	}
+	userID := s.state.Users[i].ID
+	if !slices.Contains(s.state.GroupMembers[groupID], userID) {
+		s.state.GroupMembers[groupID] = append(s.state.GroupMembers[groupID], userID)
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) removeGroupMember(w http.ResponseWriter, r *http.Request) {
+	groupID, ok := s.findGroup(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	i, ok := s.findUser(w, r.PathValue("user_id"))
+	if !ok {
+		return
+	}
+	userID := s.state.Users[i].ID
+	if !slices.Contains(s.state.GroupMembers[groupID], userID) {
+		writeError(w, http.StatusNotFound, "User is not a member of the group")
+		return
+	}
+	s.state.GroupMembers[groupID] = slices.DeleteFunc(s.state.GroupMembers[groupID], func(id int) bool {
+		return id == userID
+	})
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listRoles(w http.ResponseWriter, _ *http.Request) {
+	roles := s.state.Roles
+	if roles == nil {
+		roles = []client.RoleDetails{}
+	}
+	writeJSON(w, http.StatusOK, roles)
+}
+
+func (s *Server) createRole(w http.ResponseWriter, r *http.Request) {
+	var body client.RoleReqBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusBadRequest, "Role name is required")
+		return
+	}
+	if slices.ContainsFunc(s.state.Roles, func(role client.RoleDetails) bool { return role.Name == body.Name }) {
+		writeError(w, http.StatusConflict, "Duplicate role name")
+		return
+	}
+
+	role := client.RoleDetails{
+		UUID:        uuid.New(),
+		Name:        body.Name,
+		Description: body.Description,
+		Permissions: slices.Clone(body.Permissions),
+		Type:        "CUSTOM",
+		Status:      "ACTIVE",
+	}
+	s.state.Roles = append(s.state.Roles, role)
+	writeJSON(w, http.StatusOK, role)
+}
+
+func (s *Server) updateRole(w http.ResponseWriter, r *http.Request) {
+	i := s.roleIndex(r.PathValue("uuid"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "Role not found")
+		return
+	}
+	if s.state.Roles[i].Type != "CUSTOM" {
+		writeError(w, http.StatusBadRequest, "Only custom roles can be updated")
+		return
+	}
+	var body client.RoleReqBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusBadRequest, "Role name is required")
+		return
+	}
+	s.state.Roles[i].Name = body.Name
+	s.state.Roles[i].Description = body.Description
+	s.state.Roles[i].Permissions = slices.Clone(body.Permissions)
+	writeJSON(w, http.StatusOK, s.state.Roles[i])
+}
+
+func (s *Server) deleteRole(w http.ResponseWriter, r *http.Request) {
+	roleUUID := r.PathValue("uuid")
+	i := s.roleIndex(roleUUID)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "Role not found")
+		return
+	}
+	if s.state.Roles[i].Type != "CUSTOM" {
+		writeError(w, http.StatusBadRequest, "Only custom roles can be deleted")
+		return
+	}
+	for _, roles := range s.state.UserRoles {
+		if slices.Contains(roles, roleUUID) {
+			writeError(w, http.StatusConflict, "Role is assigned to users")
+			return
+		}
+	}
+	s.state.Roles = slices.Delete(s.state.Roles, i, i+1)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getUserRoles(w http.ResponseWriter, r *http.Request) {
+	userUUID, ok := s.findUserUUID(w, r.PathValue("uuid"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, client.UserRole{UserUUID: userUUID, RolesUUID: s.state.UserRoles[userUUID]})
+}
+
+func (s *Server) updateUserRoles(w http.ResponseWriter, r *http.Request) {
+	userUUID, ok := s.findUserUUID(w, r.PathValue("uuid"))
+	if !ok {
+		return
+	}
+	var body client.UserRoleReqBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	for _, roleUUID := range body.RolesUUIDs {
+		if s.roleIndex(roleUUID) < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Role %s does not exist", roleUUID))
+			return
+		}
+	}
+	s.state.UserRoles[userUUID] = slices.Clone(body.RolesUUIDs)
+	writeJSON(w, http.StatusOK, client.UserRole{UserUUID: userUUID, RolesUUID: s.state.UserRoles[userUUID]})
+}
+
+func (s *Server) listPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, pagination, ok := page(w, r, s.state.Permissions)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, client.PermissionsList{Permissions: permissions, Pagination: pagination})
+}
+
+func (s *Server) listTagCategories(w http.ResponseWriter, r *http.Request) {
+	categories, pagination, ok := page(w, r, s.state.TagCategories)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, client.TagCategoriesList{Categories: categories, Pagination: pagination})
+}
+
+// listTagValues supports the category_uuid:eq filter the connector lists the values of a category with.
+func (s *Server) listTagValues(w http.ResponseWriter, r *http.Request) {
+	values := s.state.TagValues
+	if filter := r.URL.Query().Get("f"); filter != "" {
+		categoryUUID, ok := strings.CutPrefix(filter, "category_uuid:eq:")
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported filter %q", filter))
+			return
+		}
+		values = slices.DeleteFunc(slices.Clone(values), func(value client.TagValue) bool {
+			return value.CategoryUUID != categoryUUID
+		})
+	}
+	values, pagination, ok := page(w, r, values)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, client.TagValuesList{Values: values, Pagination: pagination})
+}
+
+// page returns the slice of items selected by the limit and offset query parameters.
+func page[T any](w http.ResponseWriter, r *http.Request, items []T) ([]T, *client.Pagination, bool) {
+	total := len(items)
+	limit := client.DefaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, _ = strconv.Atoi(value)
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if limit <= 0 || offset < 0 {
+		writeError(w, http.StatusBadRequest, "Invalid pagination parameters")
+		return nil, nil, false
+	}
+
+	res := []T{}
+	if offset < total {
+		res = append(res, items[offset:min(offset+limit, total)]...)
+	}
+	return res, &client.Pagination{Total: total, Offset: offset, Limit: limit}, true
+}
+
+func (s *Server) getPermission(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findPermission(w, r.PathValue("uuid"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.state.Permissions[i])
+}
+
+func (s *Server) updatePermission(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findPermission(w, r.PathValue("uuid"))
+	if !ok {
+		return
+	}
+	var body client.PermissionUpdateBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	permission := &s.state.Permissions[i]
+	permission.Name = body.Name
+	permission.Actions = body.Actions
+	permission.Objects = body.Objects
+	permission.Subjects = body.Subjects
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) createPermission(w http.ResponseWriter, r *http.Request) {
+	var body client.PermissionUpdateBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Name == "" || len(body.Actions) == 0 {
+		writeError(w, http.StatusBadRequest, "Permission name and actions are required")
+		return
+	}
+
+	permission := client.Permission{
+		UUID:     uuid.New(),
+		Name:     body.Name,
+		Actions:  body.Actions,
+		Objects:  body.Objects,
+		Subjects: body.Subjects,
+	}
+	s.state.Permissions = append(s.state.Permissions, permission)
+	writeJSON(w, http.StatusOK, client.PermissionCreateResponse{UUID: permission.UUID})
+}
+
+func (s *Server) deletePermission(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.findPermission(w, r.PathValue("uuid"))
+	if !ok {
+		return
+	}
+	s.state.Permissions = slices.Delete(s.state.Permissions, i, i+1)
+	w.WriteHeader(http.StatusOK)
+}
+
+// withRoles fills in rbac_roles the way the API does when the request asks for them.
+func (s *Server) withRoles(r *http.Request, user client.User) client.User {
+	if r.URL.Query().Get("withRoles") != "true" {
+		return user
+	}
+	user.RbacRoles = nil
+	for _, roleUUID := range s.state.UserRoles[user.UUID] {
+		if i := s.roleIndex(roleUUID); i >= 0 {
+			role := s.state.Roles[i]
+			user.RbacRoles = append(user.RbacRoles, client.Role{UUID: role.UUID, Name: role.Name})
+		}
+	}
+	return user
+}
+
+func (s *Server) userIndex(id int) int {
+	return slices.IndexFunc(s.state.Users, func(user client.User) bool {
+		return user.ID == id
+	})
+}
+
+func (s *Server) roleIndex(roleUUID string) int {
+	return slices.IndexFunc(s.state.Roles, func(role client.RoleDetails) bool {
+		return role.UUID.String() == roleUUID
+	})
+}
+
+func (s *Server) findUser(w http.ResponseWriter, value string) (int, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid user id %q", value))
+		return -1, false
+	}
+	i := s.userIndex(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "User not found")
+		return -1, false
+	}
+	return i, true
+}
+
+func (s *Server) findUserUUID(w http.ResponseWriter, userUUID string) (string, bool) {
+	if !slices.ContainsFunc(s.state.Users, func(user client.User) bool { return user.UUID == userUUID }) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return "", false
+	}
+	return userUUID, true
+}
+
+func (s *Server) findGroup(w http.ResponseWriter, value string) (int, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid group id %q", value))
+		return 0, false
+	}
+	if !slices.ContainsFunc(s.state.Groups, func(group client.Group) bool { return group.ID == id }) {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) findPermission(w http.ResponseWriter, value string) (int, bool) {
+	permissionUUID, err := uuid.Parse(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid permission uuid %q", value))
+		return -1, false
+	}
+	i := slices.IndexFunc(s.state.Permissions, func(permission client.Permission) bool {
+		return permission.UUID == permissionUUID
+	})
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "Permission not found")
+		return -1, false
+	}
+	return i, true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, body any) bool {
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %s", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, client.APIError{
+		StatusCode: statusCode,
+		ErrorCode:  http.StatusText(statusCode),
+		Message:    message,
+	})
+}
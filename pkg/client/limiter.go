@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter caps the requests made by a client, both in flight at once and per second, so large tenants
+// can be synced without tripping Tenable's throttling. A zero value for either limit disables it.
+type limiter struct {
+	slots chan struct{}
+
+	mtx       sync.Mutex
+	rate      float64
+	burst     float64
+	tokens    float64
+	lastCheck time.Time
+}
+
+func newLimiter(maxConcurrent int, requestsPerSecond int) *limiter {
+	l := &limiter{}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	if requestsPerSecond > 0 {
+		l.rate = float64(requestsPerSecond)
+		l.burst = float64(requestsPerSecond)
+		l.tokens = l.burst
+		l.lastCheck = time.Now()
+	}
+	return l
+}
+
+// acquire blocks until a request may be sent. The returned function must be called once the request is done.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if err := l.waitForToken(ctx); err != nil {
+		return nil, err
+	}
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *limiter) waitForToken(ctx context.Context) error {
+	if l.rate == 0 {
+		return nil
+	}
+	for {
+		l.mtx.Lock()
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.lastCheck).Seconds()*l.rate)
+		l.lastCheck = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mtx.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mtx.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -43,6 +43,12 @@ type RoleDetails struct {
 	Status      string    `json:"status,omitempty"`
 }
 
+type RoleReqBody struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"role_permission_strings"`
+}
+
 type UserRole struct {
 	ContainerUUID string   `json:"container_uuid,omitempty"`
 	UserUUID      string   `json:"user_uuid,omitempty"`
@@ -56,6 +62,33 @@ type UserUpdateReqBody struct {
 	Enabled     bool   `json:"enabled,omitempty"`
 }
 
+type UserEnabledReqBody struct {
+	Enabled bool `json:"enabled"`
+}
+
+type ChangePasswordReqBody struct {
+	Password string `json:"password"`
+}
+
+type APIKeys struct {
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+}
+
+type UserAuthorizations struct {
+	AccountUUID       string `json:"account_uuid,omitempty"`
+	UserUUID          string `json:"user_uuid,omitempty"`
+	APIPermitted      bool   `json:"api_permitted"`
+	PasswordPermitted bool   `json:"password_permitted"`
+	SAMLPermitted     bool   `json:"saml_permitted"`
+}
+
+type UserAuthorizationsReqBody struct {
+	APIPermitted      bool `json:"api_permitted"`
+	PasswordPermitted bool `json:"password_permitted"`
+	SAMLPermitted     bool `json:"saml_permitted"`
+}
+
 type UserRoleReqBody struct {
 	RolesUUIDs []string `json:"role_uuids,omitempty"`
 }
@@ -81,8 +114,13 @@ type Group struct {
 	ContainerUUID string `json:"container_uuid,omitempty"`
 }
 
+type GroupReqBody struct {
+	Name string `json:"name"`
+}
+
 type PermissionsList struct {
 	Permissions []Permission `json:"permissions,omitempty"`
+	Pagination  *Pagination  `json:"pagination,omitempty"`
 }
 
 type Permission struct {
@@ -104,8 +142,98 @@ type PermissionUpdateBody struct {
 	Subjects []TenableObject `json:"subjects,omitempty"`
 }
 
+type TagCategoriesList struct {
+	Categories []TagCategory `json:"categories,omitempty"`
+	Pagination *Pagination   `json:"pagination,omitempty"`
+}
+
+type TagCategory struct {
+	UUID        string `json:"uuid,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type TagValuesList struct {
+	Values     []TagValue  `json:"values,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type TagValue struct {
+	UUID         string `json:"uuid,omitempty"`
+	CategoryUUID string `json:"category_uuid,omitempty"`
+	CategoryName string `json:"category_name,omitempty"`
+	Value        string `json:"value,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type PermissionCreateResponse struct {
+	UUID uuid.UUID `json:"permission_uuid"`
+}
+
 type TenableObject struct {
 	Type string    `json:"type,omitempty"`
 	UUID uuid.UUID `json:"uuid,omitempty"`
 	Name string    `json:"name,omitempty"`
 }
+
+type ScansResponse struct {
+	Scans []Scan `json:"scans"`
+}
+
+type Scan struct {
+	ID    int    `json:"id,omitempty"`
+	UUID  string `json:"uuid,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Owner string `json:"owner,omitempty"` // username of the owner
+}
+
+type PoliciesResponse struct {
+	Policies []Policy `json:"policies"`
+}
+
+type Policy struct {
+	ID    int    `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Owner string `json:"owner,omitempty"` // username of the owner
+}
+
+// ObjectPermissions is the access control list of a scan or policy.
+type ObjectPermissions struct {
+	ACLs []ACL `json:"acls"`
+}
+
+type ACL struct {
+	Type        string `json:"type,omitempty"` // user, group or default
+	ID          int    `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Owner       int    `json:"owner,omitempty"` // 1 for the owner
+	Permissions int    `json:"permissions"`
+}
+
+type CredentialsList struct {
+	Credentials []ManagedCredential `json:"credentials"`
+	Pagination  *Pagination         `json:"pagination,omitempty"`
+}
+
+type ManagedCredential struct {
+	UUID        string                 `json:"uuid,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	CreatedBy   *CredentialCreator     `json:"created_by,omitempty"`
+	Permissions []CredentialPermission `json:"permissions,omitempty"`
+}
+
+type CredentialCreator struct {
+	ID          int    `json:"id,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+type CredentialPermission struct {
+	GranteeUUID string `json:"grantee_uuid,omitempty"`
+	Type        string `json:"type,omitempty"` // user or group
+	Permissions int    `json:"permissions"`
+	Name        string `json:"name,omitempty"`
+}
+
+type CredentialPermissionsBody struct {
+	Permissions []CredentialPermission `json:"permissions"`
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Object types accepted by the permissions endpoint.
+const (
+	ObjectTypeScan   = "scan"
+	ObjectTypePolicy = "policy"
+)
+
+func (c *TenableVMClient) ListScans(ctx context.Context) ([]Scan, error) {
+	var res ScansResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, ScansPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+	_, err = c.getResourcesFromAPI(ctx, queryUrl, &res)
+	if err != nil {
+		return nil, fmt.Errorf("error listing scans: %w", err)
+	}
+
+	return res.Scans, nil
+}
+
+func (c *TenableVMClient) ListPolicies(ctx context.Context) ([]Policy, error) {
+	var res PoliciesResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, PoliciesPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+	_, err = c.getResourcesFromAPI(ctx, queryUrl, &res)
+	if err != nil {
+		return nil, fmt.Errorf("error listing policies: %w", err)
+	}
+
+	return res.Policies, nil
+}
+
+// GetObjectPermissions returns the access control list of a scan or policy.
+func (c *TenableVMClient) GetObjectPermissions(ctx context.Context, objectType string, objectID int) (*ObjectPermissions, error) {
+	var res ObjectPermissions
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(ObjectPermissionsPath, objectType, objectID))
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+	_, err = c.getResourcesFromAPI(ctx, queryUrl, &res)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s %d permissions: %w", objectType, objectID, err)
+	}
+
+	return &res, nil
+}
+
+// UpdateObjectPermissions replaces the access control list of a scan or policy. The entry flagged as owner
+// becomes the owner of the object.
+func (c *TenableVMClient) UpdateObjectPermissions(ctx context.Context, objectType string, objectID int, acls []ACL) error {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(ObjectPermissionsPath, objectType, objectID))
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, nil, ObjectPermissions{ACLs: acls})
+	if err != nil {
+		return fmt.Errorf("error updating %s %d permissions: %w", objectType, objectID, err)
+	}
+
+	return nil
+}
+
+// ListCredentials returns one page of managed credentials, along with the token of the next page.
+func (c *TenableVMClient) ListCredentials(ctx context.Context, opts PageOptions) ([]ManagedCredential, string, error) {
+	var res CredentialsList
+
+	queryUrl, err := url.JoinPath(c.baseURL, CredentialsPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating url: %w", err)
+	}
+	_, err = c.getResourcesFromAPI(ctx, queryUrl, &res, withPageOptions(opts))
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing managed credentials: %w", err)
+	}
+
+	nextToken, err := nextPageToken(res.Pagination, opts, len(res.Credentials))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res.Credentials, nextToken, nil
+}
+
+func (c *TenableVMClient) GetCredentialDetails(ctx context.Context, credentialUUID string) (*ManagedCredential, error) {
+	var res ManagedCredential
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(CredentialPath, credentialUUID))
+	if err != nil {
+		return nil, fmt.Errorf("error creating url: %w", err)
+	}
+	_, err = c.getResourcesFromAPI(ctx, queryUrl, &res)
+	if err != nil {
+		return nil, fmt.Errorf("error getting managed credential: %w", err)
+	}
+	res.UUID = credentialUUID
+
+	return &res, nil
+}
+
+// UpdateCredentialPermissions replaces the users and groups allowed to use or edit a managed credential.
+func (c *TenableVMClient) UpdateCredentialPermissions(ctx context.Context, credentialUUID string, permissions []CredentialPermission) error {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(CredentialPath, credentialUUID))
+	if err != nil {
+		return fmt.Errorf("error creating url: %w", err)
+	}
+	_, _, err = c.doRequest(ctx, http.MethodPut, queryUrl, nil, CredentialPermissionsBody{Permissions: permissions})
+	if err != nil {
+		return fmt.Errorf("error updating managed credential permissions: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// DefaultPageSize is used when the caller does not ask for a specific page size.
+const DefaultPageSize = 100
+
+// Pagination is the pagination block returned by Tenable's paginated list endpoints. Offset based endpoints
+// fill Total, Offset and Limit, cursor based endpoints return the cursor of the following page in Next.
+type Pagination struct {
+	Total  int    `json:"total,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	Next   string `json:"next,omitempty"`
+}
+
+// PageOptions selects the page to fetch. A Cursor takes precedence over Offset.
+type PageOptions struct {
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+type pageToken struct {
+	Offset int    `json:"offset,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ParsePageToken turns a token returned by a paginated client method back into the options of the page it
+// points at. An empty token selects the first page.
+func ParsePageToken(token string, size int) (PageOptions, error) {
+	opts := PageOptions{Limit: size}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultPageSize
+	}
+	if token == "" {
+		return opts, nil
+	}
+
+	var pt pageToken
+	if err := json.Unmarshal([]byte(token), &pt); err != nil {
+		return PageOptions{}, fmt.Errorf("invalid page token %q: %w", token, err)
+	}
+	opts.Offset = pt.Offset
+	opts.Cursor = pt.Cursor
+	return opts, nil
+}
+
+// nextPageToken returns the token of the page after the one fetched with opts, which held count items,
+// or an empty string if that was the last page.
+func nextPageToken(p *Pagination, opts PageOptions, count int) (string, error) {
+	var next pageToken
+	switch {
+	case p != nil && p.Next != "":
+		next.Cursor = p.Next
+	case count == 0 || opts.Cursor != "":
+		// A cursor based endpoint without a cursor for the next page is done.
+		return "", nil
+	case p != nil && p.Total > 0:
+		if opts.Offset+count >= p.Total {
+			return "", nil
+		}
+		next.Offset = opts.Offset + count
+	default:
+		// Without totals a short page is the last one. A page longer than requested means the
+		// endpoint ignored the limit and returned everything.
+		if count != opts.Limit {
+			return "", nil
+		}
+		next.Offset = opts.Offset + count
+	}
+
+	token, err := json.Marshal(next)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+func withPageOptions(opts PageOptions) ReqOpt {
+	return func(reqURL *url.URL) {
+		q := reqURL.Query()
+		if opts.Limit > 0 {
+			q.Set("limit", strconv.Itoa(opts.Limit))
+		}
+		if opts.Cursor != "" {
+			q.Set("next", opts.Cursor)
+		} else if opts.Offset > 0 {
+			q.Set("offset", strconv.Itoa(opts.Offset))
+		}
+		reqURL.RawQuery = q.Encode()
+	}
+}
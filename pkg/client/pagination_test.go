@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextPageToken(t *testing.T) {
+	testCases := []struct {
+		name       string
+		pagination *Pagination
+		opts       PageOptions
+		count      int
+		want       *PageOptions
+	}{
+		{
+			name:       "offset page with more results",
+			pagination: &Pagination{Total: 250, Offset: 100, Limit: 100},
+			opts:       PageOptions{Limit: 100, Offset: 100},
+			count:      100,
+			want:       &PageOptions{Limit: 100, Offset: 200},
+		},
+		{
+			name:       "last offset page",
+			pagination: &Pagination{Total: 250, Offset: 200, Limit: 100},
+			opts:       PageOptions{Limit: 100, Offset: 200},
+			count:      50,
+		},
+		{
+			name:       "cursor page",
+			pagination: &Pagination{Next: "abc"},
+			opts:       PageOptions{Limit: 100},
+			count:      100,
+			want:       &PageOptions{Limit: 100, Cursor: "abc"},
+		},
+		{
+			name:       "last cursor page",
+			pagination: &Pagination{},
+			opts:       PageOptions{Limit: 100, Cursor: "abc"},
+			count:      100,
+		},
+		{
+			name:  "full page without pagination block",
+			opts:  PageOptions{Limit: 100},
+			count: 100,
+			want:  &PageOptions{Limit: 100, Offset: 100},
+		},
+		{
+			name:  "endpoint ignored the limit",
+			opts:  PageOptions{Limit: 100},
+			count: 1000,
+		},
+		{
+			name:       "empty page",
+			pagination: &Pagination{Total: 250},
+			opts:       PageOptions{Limit: 100, Offset: 300},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := nextPageToken(tc.pagination, tc.opts, tc.count)
+			require.NoError(t, err)
+			if tc.want == nil {
+				require.Empty(t, token)
+				return
+			}
+			opts, err := ParsePageToken(token, tc.opts.Limit)
+			require.NoError(t, err)
+			require.Equal(t, *tc.want, opts)
+		})
+	}
+}
+
+func TestListPermissionsPages(t *testing.T) {
+	const total = 250
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		res := PermissionsList{Pagination: &Pagination{Total: total, Offset: offset, Limit: limit}}
+		for i := offset; i < min(offset+limit, total); i++ {
+			res.Permissions = append(res.Permissions, Permission{UUID: uuid.New(), Name: strconv.Itoa(i)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cli, err := NewClient(ctx, server.URL, "access", "secret")
+	require.NoError(t, err)
+
+	var (
+		names []string
+		pages int
+		token string
+	)
+	for {
+		opts, err := ParsePageToken(token, 0)
+		require.NoError(t, err)
+		permissions, next, _, err := cli.ListPermissions(ctx, opts)
+		require.NoError(t, err)
+		pages++
+		for _, permission := range permissions {
+			names = append(names, permission.Name)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	require.Equal(t, 3, pages)
+	require.Len(t, names, total)
+	require.Equal(t, "249", names[total-1])
+}
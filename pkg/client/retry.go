@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultMaxRetries = 3
+	defaultBaseDelay  = 500 * time.Millisecond
+	defaultMaxDelay   = 30 * time.Second
+)
+
+// RetryPolicy controls how failed requests are retried. Throttling (429) and transient server errors
+// (502, 503, 504) are retried with jittered exponential backoff, honoring any Retry-After sent by Tenable.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// RetryNonIdempotent allows retrying POST requests, which may create duplicates if the first attempt
+	// reached Tenable before failing.
+	RetryNonIdempotent bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  defaultBaseDelay,
+		MaxDelay:   defaultMaxDelay,
+	}
+}
+
+// nextDelay returns how long to wait before retrying the failed attempt, and false if it should not be retried.
+func (p RetryPolicy) nextDelay(method string, attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || !isRetryable(err) {
+		return 0, false
+	}
+	if !p.RetryNonIdempotent && !isIdempotent(method) {
+		return 0, false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		// Waiting less than asked only earns another 429; leave longer waits to the caller.
+		if apiErr.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+		return apiErr.RetryAfter, true
+	}
+
+	backoff := min(p.BaseDelay<<attempt, p.MaxDelay)
+	if backoff <= 0 {
+		return 0, true
+	}
+	// Equal jitter: keep half of the backoff and randomize the rest so concurrent syncs spread out.
+	half := backoff / 2
+	return half + rand.N(backoff-half+1), true //nolint:gosec // jitter does not need a cryptographic source
+}
+
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	// Transport level failures (timeouts, connection resets) surface as gRPC statuses from uhttp.
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   10 * time.Millisecond,
+	}
+
+	testCases := []struct {
+		name          string
+		failures      int
+		failureStatus int
+		retryAfter    string
+		call          func(ctx context.Context, cli *TenableVMClient) error
+		wantRequests  int32
+		wantErr       error
+	}{
+		{
+			name:          "throttled get succeeds after retry-after",
+			failures:      2,
+			failureStatus: http.StatusTooManyRequests,
+			retryAfter:    "0",
+			call:          getUser,
+			wantRequests:  3,
+		},
+		{
+			name:          "unavailable put is retried",
+			failures:      1,
+			failureStatus: http.StatusServiceUnavailable,
+			call:          updateUser,
+			wantRequests:  2,
+		},
+		{
+			name:          "retries are exhausted",
+			failures:      10,
+			failureStatus: http.StatusTooManyRequests,
+			call:          getUser,
+			wantRequests:  4,
+			wantErr:       ErrRateLimited,
+		},
+		{
+			name:          "post is not retried",
+			failures:      1,
+			failureStatus: http.StatusServiceUnavailable,
+			call:          createUser,
+			wantRequests:  1,
+			wantErr:       ErrServerError,
+		},
+		{
+			name:          "retry-after beyond the max delay is left to the caller",
+			failures:      1,
+			failureStatus: http.StatusTooManyRequests,
+			retryAfter:    "120",
+			call:          getUser,
+			wantRequests:  1,
+			wantErr:       ErrRateLimited,
+		},
+		{
+			name:          "not found is not retried",
+			failures:      1,
+			failureStatus: http.StatusNotFound,
+			call:          getUser,
+			wantRequests:  1,
+			wantErr:       ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if requests.Add(1) <= int32(tc.failures) {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.failureStatus)
+					_, _ = w.Write([]byte(`{"error":"try again"}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"id":42,"uuid":"u-42","username":"user@example.com"}`))
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			cli, err := NewClient(ctx, server.URL, "access", "secret", WithRetryPolicy(policy))
+			require.NoError(t, err)
+
+			err = tc.call(ctx, cli)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.wantRequests, requests.Load())
+		})
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"users":[]}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cli, err := NewClient(ctx, server.URL, "access", "secret", WithRequestLimits(2, 0))
+	require.NoError(t, err)
+
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := cli.UpdateUser(ctx, "42", UserUpdateReqBody{Name: "name"})
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		require.NoError(t, <-errs)
+	}
+	require.LessOrEqual(t, maxInFlight.Load(), int32(2))
+}
+
+func getUser(ctx context.Context, cli *TenableVMClient) error {
+	_, err := cli.GetUserDetails(ctx, "42")
+	return err
+}
+
+func updateUser(ctx context.Context, cli *TenableVMClient) error {
+	_, err := cli.UpdateUser(ctx, "42", UserUpdateReqBody{Name: "name"})
+	return err
+}
+
+func createUser(ctx context.Context, cli *TenableVMClient) error {
+	_, err := cli.CreateUser(ctx, NewUser{Username: "user@example.com"})
+	return err
+}
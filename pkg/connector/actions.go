@@ -0,0 +1,247 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/crypto"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	enableUserAction    = "enable_user"
+	disableUserAction   = "disable_user"
+	rotateAPIKeysAction = "rotate_api_keys"
+
+	resourceIDArg = "resource_id"
+	publicKeyArg  = "public_key"
+	forceArg      = "force"
+)
+
+var (
+	errMissingArgument = errors.New("baton-tenable-vm: missing required action argument")
+	errRotateOwnKeys   = errors.New("baton-tenable-vm: refusing to rotate the API keys the connector is using, set force to rotate them anyway")
+)
+
+var userResourceIDField = &config.Field{
+	Name:        resourceIDArg,
+	DisplayName: "User ID",
+	Description: "The ID of the Tenable VM user.",
+	IsRequired:  true,
+	Field:       &config.Field_StringField{StringField: &config.StringField{}},
+}
+
+var successField = &config.Field{
+	Name:        "success",
+	DisplayName: "Success",
+	Field:       &config.Field_BoolField{BoolField: &config.BoolField{}},
+}
+
+// registerActions adds the custom actions the connector supports to its action manager.
+func (c *Connector) registerActions(ctx context.Context) error {
+	c.actions = actions.NewActionManager(ctx)
+
+	err := c.actions.RegisterAction(ctx, enableUserAction, &v2.BatonActionSchema{
+		Name:        enableUserAction,
+		DisplayName: "Enable user",
+		Description: "Enable a disabled Tenable VM user account.",
+		Arguments:   []*config.Field{userResourceIDField},
+		ReturnTypes: []*config.Field{successField},
+	}, c.enableUser)
+	if err != nil {
+		return err
+	}
+
+	err = c.actions.RegisterAction(ctx, disableUserAction, &v2.BatonActionSchema{
+		Name:        disableUserAction,
+		DisplayName: "Disable user",
+		Description: "Disable a Tenable VM user account so it can no longer log in or use its API keys.",
+		Arguments:   []*config.Field{userResourceIDField},
+		ReturnTypes: []*config.Field{successField},
+	}, c.disableUser)
+	if err != nil {
+		return err
+	}
+
+	return c.actions.RegisterAction(ctx, rotateAPIKeysAction, &v2.BatonActionSchema{
+		Name:        rotateAPIKeysAction,
+		DisplayName: "Rotate API keys",
+		Description: "Replace the access and secret keys of a Tenable VM user. The previous keys stop working immediately.",
+		Arguments: []*config.Field{
+			userResourceIDField,
+			{
+				Name:        publicKeyArg,
+				DisplayName: "Public key",
+				Description: "JWK public key the new access and secret keys are encrypted with.",
+				IsRequired:  true,
+				Field:       &config.Field_StringField{StringField: &config.StringField{}},
+			},
+			{
+				Name:        forceArg,
+				DisplayName: "Force",
+				Description: "Rotate the keys even when they are the ones the connector is using.",
+				Field:       &config.Field_BoolField{BoolField: &config.BoolField{}},
+			},
+		},
+		ReturnTypes: []*config.Field{
+			successField,
+			{
+				Name:        "encrypted_credentials",
+				DisplayName: "Encrypted credentials",
+				Description: "The new access_key and secret_key, each encrypted with the public key.",
+				Field:       &config.Field_StringSliceField{StringSliceField: &config.StringSliceField{}},
+			},
+		},
+	}, c.rotateAPIKeys)
+}
+
+// ListActionSchemas returns the schemas of the custom actions registered on the connector.
+func (d *Connector) ListActionSchemas(ctx context.Context) ([]*v2.BatonActionSchema, annotations.Annotations, error) {
+	return d.actions.ListActionSchemas(ctx)
+}
+
+// GetActionSchema returns the schema of the named custom action.
+func (d *Connector) GetActionSchema(ctx context.Context, name string) (*v2.BatonActionSchema, annotations.Annotations, error) {
+	return d.actions.GetActionSchema(ctx, name)
+}
+
+// InvokeAction runs the named custom action with args.
+func (d *Connector) InvokeAction(ctx context.Context, name string, args *structpb.Struct) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+	return d.actions.InvokeAction(ctx, name, args)
+}
+
+// GetActionStatus returns the status of a previously invoked custom action.
+func (d *Connector) GetActionStatus(ctx context.Context, id string) (v2.BatonActionStatus, string, *structpb.Struct, annotations.Annotations, error) {
+	return d.actions.GetActionStatus(ctx, id)
+}
+
+func (c *Connector) enableUser(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	return c.setUserEnabled(ctx, args, true)
+}
+
+func (c *Connector) disableUser(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	return c.setUserEnabled(ctx, args, false)
+}
+
+func (c *Connector) setUserEnabled(ctx context.Context, args *structpb.Struct, enabled bool) (*structpb.Struct, annotations.Annotations, error) {
+	userId, err := stringArg(args, resourceIDArg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = c.client.SetUserEnabled(ctx, userId, enabled)
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: failed to set enabled to %t for user %s: %w", enabled, userId, err)
+	}
+	c.invalidateUsersCache()
+
+	ctxzap.Extract(ctx).Debug("updated user enabled state",
+		zap.String("user_id", userId),
+		zap.Bool("enabled", enabled),
+	)
+
+	rv, err := structpb.NewStruct(map[string]any{
+		"success": true,
+		"enabled": enabled,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rv, nil, nil
+}
+
+// rotateAPIKeys generates a new key pair for the user and returns it encrypted with the caller's public key, as
+// the SDK does for the PlaintextData of credential rotation. Each entry of encrypted_credentials is an
+// EncryptedData message in protojson.
+func (c *Connector) rotateAPIKeys(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	userId, err := stringArg(args, resourceIDArg)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := stringArg(args, publicKeyArg)
+	if err != nil {
+		return nil, nil, err
+	}
+	force := args.GetFields()[forceArg].GetBoolValue()
+
+	session, err := c.client.GetSession(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: failed to get the connector's own user: %w", err)
+	}
+	ownKeys := strconv.Itoa(session.ID) == userId
+	if ownKeys && !force {
+		return nil, nil, fmt.Errorf("%w: user %s", errRotateOwnKeys, userId)
+	}
+
+	em, err := crypto.NewEncryptionManager(nil, []*v2.EncryptionConfig{{
+		Config: &v2.EncryptionConfig_JwkPublicKeyConfig{
+			JwkPublicKeyConfig: &v2.EncryptionConfig_JWKPublicKeyConfig{PubKey: []byte(publicKey)},
+		},
+	}})
+	if err != nil {
+		return nil, nil, err
+	}
+	// Check the key is usable before the current keys are thrown away.
+	if _, err := em.Encrypt(ctx, &v2.PlaintextData{Name: "probe"}); err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: invalid %s: %w", publicKeyArg, err)
+	}
+
+	keys, err := c.client.GenerateAPIKeys(ctx, userId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: failed to rotate API keys for user %s: %w", userId, err)
+	}
+
+	l := ctxzap.Extract(ctx)
+	if ownKeys {
+		l.Warn("rotated the API keys the connector is using, update its configuration with the new keys",
+			zap.String("user_id", userId),
+		)
+	}
+
+	var encrypted []any
+	for _, plaintext := range []*v2.PlaintextData{
+		{Name: "access_key", Bytes: []byte(keys.AccessKey)},
+		{Name: "secret_key", Bytes: []byte(keys.SecretKey)},
+	} {
+		datas, err := em.Encrypt(ctx, plaintext)
+		if err != nil {
+			return nil, nil, fmt.Errorf("baton-tenable-vm: the API keys of user %s were rotated but could not be encrypted: %w", userId, err)
+		}
+		for _, data := range datas {
+			b, err := protojson.Marshal(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			encrypted = append(encrypted, string(b))
+		}
+	}
+
+	l.Debug("rotated user API keys", zap.String("user_id", userId))
+
+	rv, err := structpb.NewStruct(map[string]any{
+		"success":               true,
+		"encrypted_credentials": encrypted,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rv, nil, nil
+}
+
+// stringArg returns the non-empty string argument name from args.
+func stringArg(args *structpb.Struct, name string) (string, error) {
+	value, ok := args.GetFields()[name]
+	if !ok || value.GetStringValue() == "" {
+		return "", fmt.Errorf("%w: %s", errMissingArgument, name)
+	}
+	return value.GetStringValue(), nil
+}
@@ -0,0 +1,208 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/crypto/providers"
+	"github.com/conductorone/baton-sdk/pkg/crypto/providers/jwk"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestActionSchemas(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(ctx, client.DefaultBaseURL, "access", "secret")
+	require.NoError(t, err)
+
+	schemas, _, err := c.ListActionSchemas(ctx)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(schemas))
+	for _, schema := range schemas {
+		names = append(names, schema.GetName())
+		require.Equal(t, resourceIDArg, schema.GetArguments()[0].GetName())
+		require.True(t, schema.GetArguments()[0].GetIsRequired())
+	}
+	require.ElementsMatch(t, []string{enableUserAction, disableUserAction, rotateAPIKeysAction}, names)
+}
+
+func TestSetUserEnabled(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []struct {
+		name        string
+		action      string
+		args        map[string]any
+		setup       func(f *fakeTenable)
+		wantErr     error
+		wantEnabled bool
+	}{
+		{
+			name:        "enables a disabled user",
+			action:      enableUserAction,
+			args:        map[string]any{resourceIDArg: "2"},
+			setup:       func(f *fakeTenable) { f.users["2"].Enabled = false },
+			wantEnabled: true,
+		},
+		{
+			name:        "disables an enabled user",
+			action:      disableUserAction,
+			args:        map[string]any{resourceIDArg: "2"},
+			setup:       func(f *fakeTenable) { f.users["2"].Enabled = true },
+			wantEnabled: false,
+		},
+		{
+			name:    "resource_id is missing",
+			action:  disableUserAction,
+			args:    map[string]any{},
+			wantErr: errMissingArgument,
+		},
+		{
+			name:    "user does not exist",
+			action:  enableUserAction,
+			args:    map[string]any{resourceIDArg: "3"},
+			wantErr: client.ErrNotFound,
+		},
+		{
+			name:    "the api call fails",
+			action:  disableUserAction,
+			args:    map[string]any{resourceIDArg: "2"},
+			setup:   func(f *fakeTenable) { f.failures["SetUserEnabled"] = errAPI },
+			wantErr: errAPI,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFakeTenable()
+			seedUsers(f)
+			if tc.setup != nil {
+				tc.setup(f)
+			}
+			c := &Connector{client: f}
+			require.NoError(t, c.registerActions(ctx))
+			c.cachedUsers = map[string]*client.User{}
+
+			args, err := structpb.NewStruct(tc.args)
+			require.NoError(t, err)
+
+			handler := c.enableUser
+			if tc.action == disableUserAction {
+				handler = c.disableUser
+			}
+			rv, _, err := handler(ctx, args)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, map[string]any{"success": true, "enabled": tc.wantEnabled}, rv.AsMap())
+			require.Equal(t, tc.wantEnabled, f.users["2"].Enabled)
+			require.Nil(t, c.cachedUsers)
+		})
+	}
+}
+
+func TestInvokeUnknownAction(t *testing.T) {
+	ctx := context.Background()
+	c := &Connector{client: newFakeTenable()}
+	require.NoError(t, c.registerActions(ctx))
+
+	_, status, _, _, err := c.InvokeAction(ctx, "rotate_everything", &structpb.Struct{})
+	require.Error(t, err)
+	require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, status)
+}
+
+func TestRotateAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	provider, err := providers.GetEncryptionProvider(jwk.EncryptionProviderJwk)
+	require.NoError(t, err)
+	encryptionConfig, privateKey, err := provider.GenerateKey(ctx)
+	require.NoError(t, err)
+	publicKey := string(encryptionConfig.GetJwkPublicKeyConfig().GetPubKey())
+
+	errAPI := errors.New("api down")
+	testCases := []struct {
+		name      string
+		args      map[string]any
+		setup     func(f *fakeTenable)
+		wantErr   error
+		wantCalls []string
+		wantKeys  map[string]string
+	}{
+		{
+			name:      "rotates another user's keys",
+			args:      map[string]any{resourceIDArg: "2", publicKeyArg: publicKey},
+			wantCalls: []string{"GenerateAPIKeys"},
+			wantKeys:  map[string]string{"access_key": "access-2", "secret_key": "secret-2"},
+		},
+		{
+			name:    "refuses to rotate the connector's own keys",
+			args:    map[string]any{resourceIDArg: "1", publicKeyArg: publicKey},
+			wantErr: errRotateOwnKeys,
+		},
+		{
+			name:      "rotates the connector's own keys when forced",
+			args:      map[string]any{resourceIDArg: "1", publicKeyArg: publicKey, forceArg: true},
+			wantCalls: []string{"GenerateAPIKeys"},
+			wantKeys:  map[string]string{"access_key": "access-1", "secret_key": "secret-1"},
+		},
+		{
+			name:    "public key is missing",
+			args:    map[string]any{resourceIDArg: "2"},
+			wantErr: errMissingArgument,
+		},
+		{
+			name: "public key is not a jwk",
+			args: map[string]any{resourceIDArg: "2", publicKeyArg: "not a key"},
+		},
+		{
+			name:      "generating the keys fails",
+			args:      map[string]any{resourceIDArg: "2", publicKeyArg: publicKey},
+			setup:     func(f *fakeTenable) { f.failures["GenerateAPIKeys"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"GenerateAPIKeys"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeTenable()
+			seedUsers(f)
+			f.session = f.users["1"]
+			if tc.setup != nil {
+				tc.setup(f)
+			}
+
+			args, err := structpb.NewStruct(tc.args)
+			require.NoError(t, err)
+
+			rv, _, err := (&Connector{client: f}).rotateAPIKeys(ctx, args)
+			require.Equal(t, tc.wantCalls, f.calls)
+			if tc.wantKeys == nil {
+				require.Error(t, err)
+				if tc.wantErr != nil {
+					require.ErrorIs(t, err, tc.wantErr)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, rv.GetFields()["success"].GetBoolValue())
+
+			keys := make(map[string]string)
+			for _, value := range rv.GetFields()["encrypted_credentials"].GetListValue().GetValues() {
+				encrypted := &v2.EncryptedData{}
+				require.NoError(t, protojson.Unmarshal([]byte(value.GetStringValue()), encrypted))
+				plaintext, err := provider.Decrypt(ctx, encrypted, privateKey)
+				require.NoError(t, err)
+				keys[plaintext.GetName()] = string(plaintext.GetBytes())
+			}
+			require.Equal(t, tc.wantKeys, keys)
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package connector_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/conductorone/baton-tenable-vm/pkg/client/faketenable"
+	"github.com/conductorone/baton-tenable-vm/pkg/connector"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// The provisioning tests below run with the SDK HTTP cache on, the way the connector runs in production, so a
+// read a write is computed from cannot be served a response cached before an earlier write.
+
+var (
+	scanRoleUUID   = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000022")
+	reportRoleUUID = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000023")
+)
+
+func newCachedConnectorClient(ctx context.Context, t *testing.T, server *faketenable.Server) v2.GrantManagerServiceClient {
+	return v2.NewGrantManagerServiceClient(newCachedConnectorConn(ctx, t, server))
+}
+
+func newCachedConnectorConn(ctx context.Context, t *testing.T, server *faketenable.Server) *grpc.ClientConn {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "false")
+	c, err := connector.New(ctx, server.URL, faketenable.AccessKey, faketenable.SecretKey,
+		connector.WithClientOptions(client.WithRetryPolicy(client.RetryPolicy{})))
+	require.NoError(t, err)
+	return newConnectorClient(ctx, t, c)
+}
+
+// changeOutsideConnector sends a request straight to the fake API, the way a change made in the Tenable UI reaches
+// it without the connector hearing about it.
+func changeOutsideConnector(ctx context.Context, t *testing.T, server *faketenable.Server, method string, path string, body string) {
+	req, err := http.NewRequestWithContext(ctx, method, server.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("X-ApiKeys", fmt.Sprintf("accessKey=%s; secretKey=%s", faketenable.AccessKey, faketenable.SecretKey))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func resourceOf(resourceType string, id string) *v2.Resource {
+	return &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceType, Resource: id}}
+}
+
+func entitlementOf(resource *v2.Resource, slug string) *v2.Entitlement {
+	return &v2.Entitlement{
+		Id:       resource.Id.ResourceType + ":" + resource.Id.Resource + ":" + slug,
+		Resource: resource,
+		Slug:     slug,
+	}
+}
+
+func TestCachedRoleGrants(t *testing.T) {
+	ctx := context.Background()
+	state := seedState()
+	state.Roles = append(state.Roles,
+		client.RoleDetails{UUID: scanRoleUUID, Name: "Scanner", Type: "CUSTOM"},
+		client.RoleDetails{UUID: reportRoleUUID, Name: "Reporter", Type: "CUSTOM"},
+	)
+	server := faketenable.New(state)
+	defer server.Close()
+	grants := newCachedConnectorClient(ctx, t, server)
+
+	for _, role := range []uuid.UUID{scanRoleUUID, reportRoleUUID} {
+		_, err := grants.Grant(ctx, &v2.GrantManagerServiceGrantRequest{
+			Principal:   resourceOf("user", "2"),
+			Entitlement: entitlementOf(resourceOf("role", role.String()), "assigned"),
+		})
+		require.NoError(t, err)
+	}
+
+	require.ElementsMatch(t, []string{
+		basicRoleUUID.String(),
+		scanRoleUUID.String(),
+		reportRoleUUID.String(),
+	}, server.State().UserRoles[bobUUID.String()])
+}
+
+func TestCachedRoleRevokes(t *testing.T) {
+	ctx := context.Background()
+	state := seedState()
+	state.Roles = append(state.Roles,
+		client.RoleDetails{UUID: scanRoleUUID, Name: "Scanner", Type: "CUSTOM"},
+		client.RoleDetails{UUID: reportRoleUUID, Name: "Reporter", Type: "CUSTOM"},
+	)
+	state.UserRoles[bobUUID.String()] = []string{basicRoleUUID.String(), scanRoleUUID.String(), reportRoleUUID.String()}
+	server := faketenable.New(state)
+	defer server.Close()
+	grants := newCachedConnectorClient(ctx, t, server)
+
+	for _, role := range []uuid.UUID{scanRoleUUID, reportRoleUUID} {
+		_, err := grants.Revoke(ctx, &v2.GrantManagerServiceRevokeRequest{
+			Grant: &v2.Grant{
+				Principal:   resourceOf("user", "2"),
+				Entitlement: entitlementOf(resourceOf("role", role.String()), "assigned"),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []string{basicRoleUUID.String()}, server.State().UserRoles[bobUUID.String()])
+}
+
+func TestCachedPermissionGrants(t *testing.T) {
+	ctx := context.Background()
+	server := faketenable.New(seedState())
+	defer server.Close()
+	grants := newCachedConnectorClient(ctx, t, server)
+
+	scanAssigned := entitlementOf(resourceOf("permission", scanPermission.String()), "assigned")
+	for _, userId := range []string{"1", "2"} {
+		_, err := grants.Grant(ctx, &v2.GrantManagerServiceGrantRequest{
+			Principal:   resourceOf("user", userId),
+			Entitlement: scanAssigned,
+		})
+		require.NoError(t, err)
+	}
+	require.ElementsMatch(t, []uuid.UUID{carolUUID, opsGroupUUID, aliceUUID, bobUUID}, permissionSubjects(server.State(), scanPermission))
+
+	for _, principal := range []*v2.Resource{resourceOf("user", "3"), resourceOf("group", "10")} {
+		_, err := grants.Revoke(ctx, &v2.GrantManagerServiceRevokeRequest{
+			Grant: &v2.Grant{Principal: principal, Entitlement: scanAssigned},
+		})
+		require.NoError(t, err)
+	}
+	require.ElementsMatch(t, []uuid.UUID{aliceUUID, bobUUID}, permissionSubjects(server.State(), scanPermission))
+}
+
+func TestCachedPermissionGrantToNewGroup(t *testing.T) {
+	ctx := context.Background()
+	server := faketenable.New(seedState())
+	defer server.Close()
+	conn := newCachedConnectorConn(ctx, t, server)
+
+	// Listing the groups caches them.
+	_, err := v2.NewResourcesServiceClient(conn).ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{ResourceTypeId: "group"})
+	require.NoError(t, err)
+
+	// Created outside the connector, which nothing it caches hears about.
+	changeOutsideConnector(ctx, t, server, http.MethodPost, client.ListGroupsPath, `{"name":"Security"}`)
+
+	_, err = v2.NewGrantManagerServiceClient(conn).Grant(ctx, &v2.GrantManagerServiceGrantRequest{
+		Principal:   resourceOf("group", "11"),
+		Entitlement: entitlementOf(resourceOf("permission", scanPermission.String()), "assigned"),
+	})
+	require.NoError(t, err)
+	require.Len(t, permissionSubjects(server.State(), scanPermission), 3)
+}
+
+func permissionSubjects(state faketenable.State, permissionUUID uuid.UUID) []uuid.UUID {
+	var subjects []uuid.UUID
+	for _, permission := range state.Permissions {
+		if permission.UUID != permissionUUID {
+			continue
+		}
+		for _, subject := range permission.Subjects {
+			subjects = append(subjects, subject.UUID)
+		}
+	}
+	return subjects
+}
+
+func TestCachedLoginMethodGrants(t *testing.T) {
+	ctx := context.Background()
+	server := faketenable.New(seedState())
+	defer server.Close()
+	grants := newCachedConnectorClient(ctx, t, server)
+
+	_, err := grants.Revoke(ctx, &v2.GrantManagerServiceRevokeRequest{
+		Grant: &v2.Grant{
+			Principal:   resourceOf("user", "1"),
+			Entitlement: entitlementOf(resourceOf("login_method", "authorizations"), "api"),
+		},
+	})
+	require.NoError(t, err)
+	_, err = grants.Grant(ctx, &v2.GrantManagerServiceGrantRequest{
+		Principal:   resourceOf("user", "1"),
+		Entitlement: entitlementOf(resourceOf("login_method", "authorizations"), "saml"),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, client.UserAuthorizations{
+		UserUUID:          aliceUUID.String(),
+		PasswordPermitted: true,
+		SAMLPermitted:     true,
+	}, server.State().Authorizations[1])
+}
+
+func TestCachedCreateAccountAfterDelete(t *testing.T) {
+	ctx := context.Background()
+	server := faketenable.New(seedState())
+	defer server.Close()
+	accounts := v2.NewAccountManagerServiceClient(newCachedConnectorConn(ctx, t, server))
+
+	profile, err := structpb.NewStruct(map[string]any{"name": "Bob", "email": "bob@example.com"})
+	require.NoError(t, err)
+	createBob := func() *v2.CreateAccountResponse {
+		res, err := accounts.CreateAccount(ctx, &v2.CreateAccountRequest{
+			AccountInfo: &v2.AccountInfo{Profile: profile},
+			CredentialOptions: &v2.CredentialOptions{
+				Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 16}},
+			},
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	res := createBob()
+	require.False(t, res.GetSuccess().GetIsCreateAccountResult())
+	require.Empty(t, res.GetEncryptedData())
+
+	// Deleted outside the connector, which nothing it caches hears about.
+	changeOutsideConnector(ctx, t, server, http.MethodDelete, "/users/2", "")
+
+	res = createBob()
+	require.True(t, res.GetSuccess().GetIsCreateAccountResult())
+	require.NotEqual(t, "2", res.GetSuccess().GetResource().GetId().GetResource())
+}
+
+func TestCachedRoleDeleteSeesNewAssignments(t *testing.T) {
+	ctx := context.Background()
+	state := seedState()
+	state.Roles = append(state.Roles, client.RoleDetails{UUID: scanRoleUUID, Name: "Scanner", Type: "CUSTOM"})
+	server := faketenable.New(state)
+	defer server.Close()
+	conn := newCachedConnectorConn(ctx, t, server)
+
+	// Listing the users caches them without the role.
+	_, err := v2.NewResourcesServiceClient(conn).ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{ResourceTypeId: "user"})
+	require.NoError(t, err)
+
+	// Assigned outside the connector, which nothing it caches hears about.
+	changeOutsideConnector(ctx, t, server, http.MethodPut, fmt.Sprintf(client.UserRolePath, bobUUID),
+		fmt.Sprintf(`{"role_uuids":[%q,%q]}`, basicRoleUUID, scanRoleUUID))
+
+	_, err = v2.NewResourceManagerServiceClient(conn).DeleteResource(ctx, &v2.DeleteResourceRequest{
+		ResourceId: &v2.ResourceId{ResourceType: "role", Resource: scanRoleUUID.String()},
+	})
+	require.ErrorContains(t, err, "is assigned to 1 users")
+	require.Contains(t, server.State().UserRoles[bobUUID.String()], scanRoleUUID.String())
+}
+
+func TestCachedUserTypeRevoke(t *testing.T) {
+	ctx := context.Background()
+	server := faketenable.New(seedState())
+	defer server.Close()
+	grants := newCachedConnectorClient(ctx, t, server)
+	revokeAdministrator := func() {
+		_, err := grants.Revoke(ctx, &v2.GrantManagerServiceRevokeRequest{
+			Grant: &v2.Grant{
+				Principal:   resourceOf("user", "2"),
+				Entitlement: entitlementOf(resourceOf("user_type", "64"), "assigned"),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	// Bob is read, and cached, as a Basic user.
+	revokeAdministrator()
+
+	// Promoted outside the connector, which nothing it caches hears about.
+	changeOutsideConnector(ctx, t, server, http.MethodPut, "/users/2", `{"permissions":64}`)
+
+	revokeAdministrator()
+	for _, user := range server.State().Users {
+		if user.ID == 2 {
+			require.Equal(t, connector.BasicUserRole, user.Permissions)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+)
+
+const grantedEntitlement = "granted"
+
+// capabilityBuilder syncs every distinct permission string held by a role, so access reviews can tell who may
+// perform a given action. Capabilities are granted to roles and expand to the users assigned the role.
+type capabilityBuilder struct {
+	client        client.TenableAPI
+	cachedRoles   []*client.RoleDetails
+	rolesLastLoad time.Time
+	rolesMtx      sync.Mutex
+}
+
+func (o *capabilityBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return capabilityResourceType
+}
+
+// List returns one capability per permission string found on any role.
+func (o *capabilityBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	roles, annos, err := o.loadRolesCache(ctx)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	var capabilities []string
+	for _, role := range roles {
+		for _, permission := range role.Permissions {
+			if !slices.Contains(capabilities, permission) {
+				capabilities = append(capabilities, permission)
+			}
+		}
+	}
+	slices.Sort(capabilities)
+
+	var resources []*v2.Resource
+	for _, capability := range capabilities {
+		capabilityResource, err := rs.NewResource(
+			capability,
+			capabilityResourceType,
+			capability,
+			rs.WithDescription(fmt.Sprintf("Tenable VM role permission %s", capability)),
+			rs.WithParentResourceID(parentResourceID),
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, capabilityResource)
+	}
+	return resources, "", nil, nil
+}
+
+func (o *capabilityBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	displayName := fmt.Sprintf("%s %s", resource.DisplayName, grantedEntitlement)
+	description := fmt.Sprintf("Granted the %s permission through a role", resource.DisplayName)
+	entitlements := []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(
+			resource,
+			grantedEntitlement,
+			entitlement.WithDescription(description),
+			entitlement.WithDisplayName(displayName),
+		),
+	}
+
+	return entitlements, "", nil, nil
+}
+
+// Grants returns a grant for every role holding the permission string. The grants expand to the users assigned
+// the role, and are immutable since they change by editing the role.
+func (o *capabilityBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	roles, annos, err := o.loadRolesCache(ctx)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	var grants []*v2.Grant
+	for _, role := range roles {
+		if !slices.Contains(role.Permissions, resource.Id.Resource) {
+			continue
+		}
+		roleResourceID := &v2.ResourceId{
+			ResourceType: roleResourceType.Id,
+			Resource:     role.UUID.String(),
+		}
+		expandableMsg := &v2.GrantExpandable{
+			EntitlementIds: []string{
+				fmt.Sprintf("%s:%s:%s", roleResourceType.Id, roleResourceID.Resource, rolePermissionName),
+			},
+		}
+		grants = append(grants, grant.NewGrant(resource, grantedEntitlement, roleResourceID,
+			grant.WithAnnotation(expandableMsg, &v2.GrantImmutable{})))
+	}
+	return grants, "", nil, nil
+}
+
+// loadRolesCache lists the roles once, they are shared by List and the Grants of every capability.
+func (o *capabilityBuilder) loadRolesCache(ctx context.Context) ([]*client.RoleDetails, annotations.Annotations, error) {
+	o.rolesMtx.Lock()
+	defer o.rolesMtx.Unlock()
+
+	if o.cachedRoles != nil && time.Since(o.rolesLastLoad) < TTL*time.Minute {
+		return o.cachedRoles, nil, nil
+	}
+
+	roles, annos, err := o.client.GetRoles(ctx)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	o.cachedRoles = roles
+	o.rolesLastLoad = time.Now()
+	return roles, nil, nil
+}
+
+func newCapabilityBuilder(c client.TenableAPI) *capabilityBuilder {
+	return &capabilityBuilder{
+		client: c,
+	}
+}
@@ -0,0 +1,53 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilityGrants(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	f.roles = []*client.RoleDetails{
+		{UUID: uuid.MustParse(testRoleUUID), Name: "Scanner", Permissions: []string{"SCAN.VIEW", "SCAN.MANAGE"}},
+		{UUID: uuid.MustParse(otherRoleUUID), Name: "Viewer", Permissions: []string{"SCAN.VIEW"}},
+		{UUID: uuid.MustParse(basicRoleUUID), Name: "Basic"},
+	}
+	b := newCapabilityBuilder(f)
+
+	resources, _, _, err := b.List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	var ids []string
+	for _, resource := range resources {
+		ids = append(ids, resource.Id.Resource)
+	}
+	require.Equal(t, []string{"SCAN.MANAGE", "SCAN.VIEW"}, ids)
+
+	entitlements, _, _, err := b.Entitlements(ctx, resources[1], nil)
+	require.NoError(t, err)
+	require.Len(t, entitlements, 1)
+	// Permission strings are granted by editing a role, which the connector does not do.
+	require.Empty(t, entitlements[0].GrantableTo)
+
+	grants, _, _, err := b.Grants(ctx, resources[1], &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 2)
+	for i, roleUUID := range []string{testRoleUUID, otherRoleUUID} {
+		require.Equal(t, &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: roleUUID}, grants[i].Principal.Id)
+
+		expandable := &v2.GrantExpandable{}
+		annos := annotations.Annotations(grants[i].Annotations)
+		ok, err := annos.Pick(expandable)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, []string{"role:" + roleUUID + ":assigned"}, expandable.EntitlementIds)
+		require.True(t, annos.Contains(&v2.GrantImmutable{}))
+	}
+}
@@ -2,12 +2,16 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-tenable-vm/pkg/client"
@@ -16,10 +20,67 @@ import (
 const TTL = 5 // in minutes
 
 type Connector struct {
-	client         *client.TenableVMClient
-	cachedUsers    map[string]*client.User
-	usersTimestamp time.Time
-	usersMtx       sync.Mutex
+	client     client.TenableAPI
+	clientOpts []client.Option
+	// ownershipTarget is the UUID or username of the user receiving the objects owned by deleted users.
+	ownershipTarget string
+	// passwordPolicy applies to generated passwords, the zero value stands for DefaultPasswordPolicy.
+	passwordPolicy PasswordPolicy
+	// forceRoleDeletion lets custom roles still assigned to users be deleted, after unassigning them.
+	forceRoleDeletion bool
+	actions           *actions.ActionManager
+	cachedUsers       map[string]*client.User
+	usersTimestamp    time.Time
+	usersMtx          sync.Mutex
+	// cachedGroups maps group UUIDs to group ids.
+	cachedGroups         map[string]string
+	groupsTimestamp      time.Time
+	groupsMtx            sync.Mutex
+	cachedPermissions    []*client.Permission
+	permissionsByUUID    map[string]*client.Permission
+	permissionsTimestamp time.Time
+	permissionsMtx       sync.Mutex
+}
+
+// Option configures optional connector behaviour.
+type Option func(*Connector)
+
+// WithClientOptions forwards options to the Tenable VM client, e.g. retry and request limits.
+func WithClientOptions(opts ...client.Option) Option {
+	return func(c *Connector) {
+		c.clientOpts = append(c.clientOpts, opts...)
+	}
+}
+
+// WithOwnershipTransferTo sets the user, by UUID or username, who takes over the scans, policies and managed
+// credentials of deleted users.
+func WithOwnershipTransferTo(userRef string) Option {
+	return func(c *Connector) {
+		c.ownershipTarget = strings.TrimSpace(userRef)
+	}
+}
+
+// WithPasswordPolicy sets the policy passwords generated for new accounts and rotations must satisfy.
+func WithPasswordPolicy(policy PasswordPolicy) Option {
+	return func(c *Connector) {
+		c.passwordPolicy = policy
+	}
+}
+
+// WithForceRoleDeletion allows deleting custom roles that are still assigned to users. The role is taken away
+// from those users first.
+func WithForceRoleDeletion(force bool) Option {
+	return func(c *Connector) {
+		c.forceRoleDeletion = force
+	}
+}
+
+// getPasswordPolicy returns the configured password policy, or the default one when none was set.
+func (c *Connector) getPasswordPolicy() PasswordPolicy {
+	if c.passwordPolicy.MinLength == 0 {
+		return DefaultPasswordPolicy()
+	}
+	return c.passwordPolicy
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
@@ -29,6 +90,11 @@ func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.Reso
 		newRoleBuilder(d.client, d),
 		newGroupBuilder(d.client),
 		newPermissionBuilder(d.client, d),
+		newLoginMethodBuilder(d.client, d),
+		newUserTypeBuilder(d.client, d),
+		newCapabilityBuilder(d.client),
+		newTagCategoryBuilder(d.client),
+		newTagValueBuilder(d.client, d),
 	}
 }
 
@@ -38,18 +104,20 @@ func (d *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.R
 	return "", nil, nil
 }
 
-func (c *Connector) cacheUsers(ctx context.Context) (annotations.Annotations, error) {
+// cacheUsers returns the users keyed by UUID, read again once the cache expires or is invalidated. The map is
+// never modified once cached, so callers use it without holding usersMtx.
+func (c *Connector) cacheUsers(ctx context.Context) (map[string]*client.User, annotations.Annotations, error) {
 	c.usersMtx.Lock()
 	defer c.usersMtx.Unlock()
 
 	if c.cachedUsers != nil && time.Since(c.usersTimestamp) < TTL*time.Minute {
-		return nil, nil
+		return c.cachedUsers, nil, nil
 	}
 
 	usersToCache := make(map[string]*client.User)
 	users, annos, err := c.client.GetUsers(ctx)
 	if err != nil {
-		return annos, fmt.Errorf("error creating users cache %w", err)
+		return nil, annos, fmt.Errorf("error creating users cache %w", err)
 	}
 
 	for _, user := range users {
@@ -58,9 +126,101 @@ func (c *Connector) cacheUsers(ctx context.Context) (annotations.Annotations, er
 
 	c.cachedUsers = usersToCache
 	c.usersTimestamp = time.Now()
+	return usersToCache, nil, nil
+}
+
+// invalidateUsersCache drops the cached users so the next lookup sees accounts created or deleted since.
+func (c *Connector) invalidateUsersCache() {
+	c.usersMtx.Lock()
+	defer c.usersMtx.Unlock()
+	c.cachedUsers = nil
+}
+
+// cacheGroups returns the group ids keyed by group UUID, shared by everything resolving permission subjects. Like
+// the users, the map is never modified once cached.
+func (c *Connector) cacheGroups(ctx context.Context) (map[string]string, annotations.Annotations, error) {
+	c.groupsMtx.Lock()
+	defer c.groupsMtx.Unlock()
+
+	if c.cachedGroups != nil && time.Since(c.groupsTimestamp) < TTL*time.Minute {
+		return c.cachedGroups, nil, nil
+	}
+
+	groups, annos, err := c.client.GetGroups(ctx)
+	if err != nil {
+		return nil, annos, fmt.Errorf("error creating groups cache %w", err)
+	}
+	groupsToCache := make(map[string]string, len(groups))
+	for _, group := range groups {
+		groupsToCache[group.UUID] = strconv.Itoa(group.ID)
+	}
+
+	c.cachedGroups = groupsToCache
+	c.groupsTimestamp = time.Now()
+	return groupsToCache, nil, nil
+}
+
+// cachePermissions returns every permission, with its objects and subjects as listed, read once for the
+// permission and tag value grants. The slice is never modified once cached.
+func (c *Connector) cachePermissions(ctx context.Context) ([]*client.Permission, annotations.Annotations, error) {
+	c.permissionsMtx.Lock()
+	defer c.permissionsMtx.Unlock()
+
+	annos, err := c.loadPermissions(ctx)
+	return c.cachedPermissions, annos, err
+}
+
+// cachedPermission returns the cached permission with the UUID, or nil when it was not listed.
+func (c *Connector) cachedPermission(ctx context.Context, permissionUUID string) (*client.Permission, annotations.Annotations, error) {
+	c.permissionsMtx.Lock()
+	defer c.permissionsMtx.Unlock()
+
+	annos, err := c.loadPermissions(ctx)
+	return c.permissionsByUUID[permissionUUID], annos, err
+}
+
+// loadPermissions pages through the permissions once the cache expires or is invalidated. permissionsMtx must be
+// held.
+func (c *Connector) loadPermissions(ctx context.Context) (annotations.Annotations, error) {
+	if c.cachedPermissions != nil && time.Since(c.permissionsTimestamp) < TTL*time.Minute {
+		return nil, nil
+	}
+
+	permissionsToCache := []*client.Permission{}
+	permissionsByUUID := make(map[string]*client.Permission)
+	token := ""
+	for {
+		pageOpts, err := client.ParsePageToken(token, 0)
+		if err != nil {
+			return nil, err
+		}
+		permissions, nextToken, annos, err := c.client.ListPermissions(ctx, pageOpts)
+		if err != nil {
+			return annos, fmt.Errorf("error creating permissions cache %w", err)
+		}
+		for _, permission := range permissions {
+			permissionsToCache = append(permissionsToCache, &permission)
+			permissionsByUUID[permission.UUID.String()] = &permission
+		}
+		if nextToken == "" {
+			break
+		}
+		token = nextToken
+	}
+
+	c.cachedPermissions = permissionsToCache
+	c.permissionsByUUID = permissionsByUUID
+	c.permissionsTimestamp = time.Now()
 	return nil, nil
 }
 
+// invalidatePermissionsCache drops the cached permissions so the next read sees the subjects changed since.
+func (c *Connector) invalidatePermissionsCache() {
+	c.permissionsMtx.Lock()
+	defer c.permissionsMtx.Unlock()
+	c.cachedPermissions = nil
+}
+
 // Metadata returns metadata about the connector.
 func (d *Connector) Metadata(_ context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
@@ -88,24 +248,171 @@ func (d *Connector) Metadata(_ context.Context) (*v2.ConnectorMetadata, error) {
 					Placeholder: "Email",
 					Order:       2,
 				},
+				"username": {
+					DisplayName: "Username",
+					Required:    false,
+					Description: "The login of the user, when it should differ from the email.",
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+					},
+					Placeholder: "Username",
+					Order:       3,
+				},
+				"permissions": {
+					DisplayName: "Permission level",
+					Required:    false,
+					Description: "The legacy permission level of the user: 16 Basic, 24 Scan Operator, 32 Standard, 40 Scan Manager or 64 Administrator. Defaults to Basic.",
+					Field: &v2.ConnectorAccountCreationSchema_Field_IntField{
+						IntField: &v2.ConnectorAccountCreationSchema_IntField{},
+					},
+					Placeholder: "16",
+					Order:       4,
+				},
+				"role": {
+					DisplayName: "Role",
+					Required:    false,
+					Description: "The name or UUID of the RBAC role to assign instead of a permission level.",
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+					},
+					Placeholder: "Role",
+					Order:       5,
+				},
+				"groups": {
+					DisplayName: "Groups",
+					Required:    false,
+					Description: "The names or IDs of the groups to add the user to.",
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringListField{
+						StringListField: &v2.ConnectorAccountCreationSchema_StringListField{},
+					},
+					Placeholder: "Groups",
+					Order:       6,
+				},
+				"saml_only": {
+					DisplayName: "SAML only",
+					Required:    false,
+					Description: "Only allow the user to log in through SAML. No password is returned.",
+					Field: &v2.ConnectorAccountCreationSchema_Field_BoolField{
+						BoolField: &v2.ConnectorAccountCreationSchema_BoolField{},
+					},
+					Order: 7,
+				},
 			},
 		},
 	}, nil
 }
 
-// Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
-// to be sure that they are valid.
+// Validate is called to ensure that the connector is properly configured. It checks that the API keys belong to
+// an Administrator and that every endpoint the resource syncers list from can be read with them.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
-	return nil, nil
+	session, err := d.client.GetSession(ctx)
+	if err != nil {
+		if errors.Is(err, client.ErrUnauthorized) {
+			return nil, fmt.Errorf("baton-tenable-vm: the access key and secret key were rejected, check that they are correct and have not been revoked: %w", err)
+		}
+		return nil, fmt.Errorf("baton-tenable-vm: failed to validate the API keys: %w", err)
+	}
+
+	var (
+		missing   []string
+		deniedErr error
+	)
+	if session.Permissions < AdministratorUserRole {
+		missing = append(missing, fmt.Sprintf("the API keys belong to %s with permissions %d, an Administrator (%d) is required",
+			session.Username, session.Permissions, AdministratorUserRole))
+	}
+	for _, probe := range d.endpointProbes() {
+		err := probe.check(ctx)
+		switch {
+		case err == nil:
+		case errors.Is(err, client.ErrForbidden), errors.Is(err, client.ErrUnauthorized):
+			missing = append(missing, fmt.Sprintf("access to %s", probe.endpoint))
+			if deniedErr == nil {
+				deniedErr = err
+			}
+		default:
+			return nil, fmt.Errorf("baton-tenable-vm: failed to validate access to %s: %w", probe.endpoint, err)
+		}
+	}
+	if len(missing) == 0 {
+		if d.ownershipTarget != "" {
+			if _, err := d.ownershipTransferTarget(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	if deniedErr == nil {
+		deniedErr = client.ErrForbidden
+	}
+	return nil, fmt.Errorf("baton-tenable-vm: the API keys are missing required privileges: %s: %w", strings.Join(missing, "; "), deniedErr)
+}
+
+type endpointProbe struct {
+	endpoint string
+	check    func(ctx context.Context) error
+}
+
+// endpointProbes reads one page from each endpoint listed during a sync.
+func (d *Connector) endpointProbes() []endpointProbe {
+	return []endpointProbe{
+		{
+			endpoint: "GET " + client.BaseUsersPath,
+			check: func(ctx context.Context) error {
+				_, _, err := d.client.GetUsers(ctx)
+				return err
+			},
+		},
+		{
+			endpoint: "GET " + client.ListGroupsPath,
+			check: func(ctx context.Context) error {
+				_, _, err := d.client.GetGroups(ctx)
+				return err
+			},
+		},
+		{
+			endpoint: "GET " + client.RolesPath,
+			check: func(ctx context.Context) error {
+				_, _, err := d.client.GetRoles(ctx)
+				return err
+			},
+		},
+		{
+			endpoint: "GET " + client.PermissionsPath,
+			check: func(ctx context.Context) error {
+				_, _, _, err := d.client.ListPermissions(ctx, client.PageOptions{Limit: 1})
+				return err
+			},
+		},
+		{
+			endpoint: "GET " + client.TagCategoriesPath,
+			check: func(ctx context.Context) error {
+				_, _, _, err := d.client.ListTagCategories(ctx, client.PageOptions{Limit: 1})
+				return err
+			},
+		},
+	}
 }
 
 // New returns a new instance of the connector.
-func New(ctx context.Context, accessKey, secretKey string) (*Connector, error) {
-	client, err := client.NewClient(ctx, accessKey, secretKey)
+func New(ctx context.Context, baseURL, accessKey, secretKey string, opts ...Option) (*Connector, error) {
+	connector := &Connector{}
+	for _, opt := range opts {
+		opt(connector)
+	}
+	if err := connector.getPasswordPolicy().Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := client.NewClient(ctx, baseURL, accessKey, secretKey, connector.clientOpts...)
 	if err != nil {
 		return nil, err
 	}
-	return &Connector{
-		client: client,
-	}, nil
+	connector.client = client
+
+	if err := connector.registerActions(ctx); err != nil {
+		return nil, err
+	}
+	return connector, nil
 }
@@ -0,0 +1,99 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	errAPI := errors.New("api down")
+	forbidden := func(path string) error {
+		return &client.APIError{StatusCode: http.StatusForbidden, Method: http.MethodGet, Path: path}
+	}
+	testCases := []struct {
+		name        string
+		setup       func(f *fakeTenable)
+		wantErr     error
+		wantMissing []string
+	}{
+		{
+			name: "administrator with access to every endpoint",
+		},
+		{
+			name:    "keys are rejected",
+			setup:   func(f *fakeTenable) { f.session = nil },
+			wantErr: client.ErrUnauthorized,
+		},
+		{
+			name:        "keys belong to a standard user",
+			setup:       func(f *fakeTenable) { f.session.Permissions = 32 },
+			wantErr:     client.ErrForbidden,
+			wantMissing: []string{"permissions 32, an Administrator (64) is required"},
+		},
+		{
+			name: "roles and permissions are forbidden",
+			setup: func(f *fakeTenable) {
+				f.failures["GetRoles"] = forbidden(client.RolesPath)
+				f.failures["ListPermissions"] = forbidden(client.PermissionsPath)
+			},
+			wantErr:     client.ErrForbidden,
+			wantMissing: []string{"access to GET " + client.RolesPath, "access to GET " + client.PermissionsPath},
+		},
+		{
+			name:    "probe fails for another reason",
+			setup:   func(f *fakeTenable) { f.failures["GetGroups"] = errAPI },
+			wantErr: errAPI,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeTenable()
+			f.session = &client.User{ID: 1, Username: "admin@example.com", Permissions: AdministratorUserRole}
+			if tc.setup != nil {
+				tc.setup(f)
+			}
+
+			annos, err := (&Connector{client: f}).Validate(context.Background())
+			require.Empty(t, annos)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+			for _, missing := range tc.wantMissing {
+				require.ErrorContains(t, err, missing)
+			}
+		})
+	}
+}
+
+func TestUsersCacheInvalidatedConcurrently(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedUsers(f)
+	c := &Connector{client: f}
+	b := newUserBuilder(f, c)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			c.invalidateUsersCache()
+		}
+	}()
+	for range 200 {
+		users, _, _, err := b.List(ctx, nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+	}
+	wg.Wait()
+}
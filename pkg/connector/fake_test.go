@@ -0,0 +1,575 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeTenable is an in-memory client.TenableAPI. Methods listed in failures return the given error
+// instead of touching the state, and every mutating call is recorded in calls.
+type fakeTenable struct {
+	session       *client.User
+	users         map[string]*client.User
+	userRoles     map[string][]string
+	roles         []*client.RoleDetails
+	groups        map[string]*client.Group
+	groupMembers  map[string][]string
+	permissions   map[string]*client.Permission
+	tagCategories []client.TagCategory
+	tagValues     []client.TagValue
+	scans         []client.Scan
+	policies      []client.Policy
+	objectACLs    map[string][]client.ACL
+	credentials   map[string]*client.ManagedCredential
+	passwords     map[string]string
+	// authorizations of users without an entry are all false.
+	authorizations map[string]*client.UserAuthorizations
+
+	failures map[string]error
+	calls    []string
+	// onCall, when set, runs as each recorded call is made, to change the state under the connector.
+	onCall func(method string)
+}
+
+var _ client.TenableAPI = (*fakeTenable)(nil)
+
+func newFakeTenable() *fakeTenable {
+	return &fakeTenable{
+		users:          make(map[string]*client.User),
+		userRoles:      make(map[string][]string),
+		groups:         make(map[string]*client.Group),
+		groupMembers:   make(map[string][]string),
+		permissions:    make(map[string]*client.Permission),
+		objectACLs:     make(map[string][]client.ACL),
+		credentials:    make(map[string]*client.ManagedCredential),
+		passwords:      make(map[string]string),
+		authorizations: make(map[string]*client.UserAuthorizations),
+		failures:       make(map[string]error),
+	}
+}
+
+func (f *fakeTenable) fail(method string) error {
+	return f.failures[method]
+}
+
+func (f *fakeTenable) record(method string) {
+	f.calls = append(f.calls, method)
+	if f.onCall != nil {
+		f.onCall(method)
+	}
+}
+
+func notFound(path string) error {
+	return &client.APIError{StatusCode: http.StatusNotFound, Method: http.MethodGet, Path: path}
+}
+
+func (f *fakeTenable) GetSession(_ context.Context) (*client.User, error) {
+	if err := f.fail("GetSession"); err != nil {
+		return nil, err
+	}
+	if f.session == nil {
+		return nil, &client.APIError{StatusCode: http.StatusUnauthorized, Method: http.MethodGet, Path: client.SessionPath}
+	}
+	ret := *f.session
+	return &ret, nil
+}
+
+func (f *fakeTenable) GetUsers(_ context.Context) ([]client.User, annotations.Annotations, error) {
+	if err := f.fail("GetUsers"); err != nil {
+		return nil, nil, err
+	}
+	var users []client.User
+	for _, user := range f.users {
+		users = append(users, *user)
+	}
+	return users, nil, nil
+}
+
+func (f *fakeTenable) GetUserDetails(_ context.Context, userId string) (*client.User, error) {
+	if err := f.fail("GetUserDetails"); err != nil {
+		return nil, err
+	}
+	user, ok := f.users[userId]
+	if !ok {
+		return nil, notFound("/users/" + userId)
+	}
+	ret := *user
+	return &ret, nil
+}
+
+func (f *fakeTenable) CreateUser(_ context.Context, newUser client.NewUser) (*client.User, error) {
+	f.record("CreateUser")
+	if err := f.fail("CreateUser"); err != nil {
+		return nil, err
+	}
+	id := len(f.users) + 1
+	user := &client.User{
+		ID:          id,
+		UUID:        "user-uuid-" + strconv.Itoa(id),
+		Username:    newUser.Username,
+		Email:       newUser.Email,
+		Name:        newUser.Name,
+		Permissions: newUser.Permissions,
+		Enabled:     true,
+	}
+	f.users[strconv.Itoa(id)] = user
+	ret := *user
+	return &ret, nil
+}
+
+func (f *fakeTenable) UpdateUser(_ context.Context, userId string, body client.UserUpdateReqBody) (*client.User, error) {
+	f.record("UpdateUser")
+	if err := f.fail("UpdateUser"); err != nil {
+		return nil, err
+	}
+	user, ok := f.users[userId]
+	if !ok {
+		return nil, notFound("/users/" + userId)
+	}
+	if body.Permissions != 0 {
+		user.Permissions = body.Permissions
+	}
+	ret := *user
+	return &ret, nil
+}
+
+func (f *fakeTenable) SetUserEnabled(_ context.Context, userId string, enabled bool) error {
+	f.record("SetUserEnabled")
+	if err := f.fail("SetUserEnabled"); err != nil {
+		return err
+	}
+	user, ok := f.users[userId]
+	if !ok {
+		return notFound("/users/" + userId + "/enabled")
+	}
+	user.Enabled = enabled
+	return nil
+}
+
+func (f *fakeTenable) ChangeUserPassword(_ context.Context, userId string, password string) error {
+	f.record("ChangeUserPassword")
+	if err := f.fail("ChangeUserPassword"); err != nil {
+		return err
+	}
+	if _, ok := f.users[userId]; !ok {
+		return notFound("/users/" + userId + "/chpasswd")
+	}
+	f.passwords[userId] = password
+	return nil
+}
+
+func (f *fakeTenable) GenerateAPIKeys(_ context.Context, userId string) (*client.APIKeys, error) {
+	f.record("GenerateAPIKeys")
+	if err := f.fail("GenerateAPIKeys"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.users[userId]; !ok {
+		return nil, notFound("/users/" + userId + "/keys")
+	}
+	return &client.APIKeys{AccessKey: "access-" + userId, SecretKey: "secret-" + userId}, nil
+}
+
+func (f *fakeTenable) GetUserAuthorizations(_ context.Context, userId string) (*client.UserAuthorizations, error) {
+	if err := f.fail("GetUserAuthorizations"); err != nil {
+		return nil, err
+	}
+	user, ok := f.users[userId]
+	if !ok {
+		return nil, notFound("/users/" + userId + "/authorizations")
+	}
+	authorizations := client.UserAuthorizations{UserUUID: user.UUID}
+	if current, ok := f.authorizations[userId]; ok {
+		authorizations = *current
+	}
+	return &authorizations, nil
+}
+
+func (f *fakeTenable) UpdateUserAuthorizations(_ context.Context, userId string, authorizations client.UserAuthorizations) error {
+	f.record("UpdateUserAuthorizations")
+	if err := f.fail("UpdateUserAuthorizations"); err != nil {
+		return err
+	}
+	if _, ok := f.users[userId]; !ok {
+		return notFound("/users/" + userId + "/authorizations")
+	}
+	f.authorizations[userId] = &authorizations
+	return nil
+}
+
+func (f *fakeTenable) DeleteUser(_ context.Context, userId string) error {
+	f.record("DeleteUser")
+	if err := f.fail("DeleteUser"); err != nil {
+		return err
+	}
+	if _, ok := f.users[userId]; !ok {
+		return notFound("/users/" + userId)
+	}
+	delete(f.users, userId)
+	return nil
+}
+
+func (f *fakeTenable) GetRoles(_ context.Context) ([]*client.RoleDetails, annotations.Annotations, error) {
+	if err := f.fail("GetRoles"); err != nil {
+		return nil, nil, err
+	}
+	return f.roles, nil, nil
+}
+
+func (f *fakeTenable) CreateRole(_ context.Context, role client.RoleReqBody) (*client.RoleDetails, error) {
+	f.record("CreateRole")
+	if err := f.fail("CreateRole"); err != nil {
+		return nil, err
+	}
+	created := &client.RoleDetails{
+		UUID:        uuid.New(),
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: slices.Clone(role.Permissions),
+		Type:        "CUSTOM",
+	}
+	f.roles = append(f.roles, created)
+	ret := *created
+	return &ret, nil
+}
+
+func (f *fakeTenable) UpdateRole(_ context.Context, roleUUID string, role client.RoleReqBody) (*client.RoleDetails, error) {
+	f.record("UpdateRole")
+	if err := f.fail("UpdateRole"); err != nil {
+		return nil, err
+	}
+	for _, existing := range f.roles {
+		if existing.UUID.String() == roleUUID {
+			existing.Name = role.Name
+			existing.Description = role.Description
+			existing.Permissions = slices.Clone(role.Permissions)
+			ret := *existing
+			return &ret, nil
+		}
+	}
+	return nil, notFound("/access-control/v1/roles/" + roleUUID)
+}
+
+func (f *fakeTenable) DeleteRole(_ context.Context, roleUUID string) error {
+	f.record("DeleteRole")
+	if err := f.fail("DeleteRole"); err != nil {
+		return err
+	}
+	i := slices.IndexFunc(f.roles, func(role *client.RoleDetails) bool { return role.UUID.String() == roleUUID })
+	if i < 0 {
+		return notFound("/access-control/v1/roles/" + roleUUID)
+	}
+	f.roles = slices.Delete(f.roles, i, i+1)
+	return nil
+}
+
+func (f *fakeTenable) GetUserRoles(_ context.Context, userUUID string) (*client.UserRole, error) {
+	if err := f.fail("GetUserRoles"); err != nil {
+		return nil, err
+	}
+	return &client.UserRole{UserUUID: userUUID, RolesUUID: slices.Clone(f.userRoles[userUUID])}, nil
+}
+
+func (f *fakeTenable) UpdateUserRoles(_ context.Context, userUUID string, roleUUIDs []string) (*client.UserRole, error) {
+	f.record("UpdateUserRoles")
+	if err := f.fail("UpdateUserRoles"); err != nil {
+		return nil, err
+	}
+	f.userRoles[userUUID] = slices.Clone(roleUUIDs)
+	return &client.UserRole{UserUUID: userUUID, RolesUUID: slices.Clone(f.userRoles[userUUID])}, nil
+}
+
+func (f *fakeTenable) GetGroups(_ context.Context) ([]client.Group, annotations.Annotations, error) {
+	if err := f.fail("GetGroups"); err != nil {
+		return nil, nil, err
+	}
+	var groups []client.Group
+	for _, group := range f.groups {
+		groups = append(groups, *group)
+	}
+	return groups, nil, nil
+}
+
+func (f *fakeTenable) CreateGroup(_ context.Context, name string) (*client.Group, error) {
+	f.record("CreateGroup")
+	if err := f.fail("CreateGroup"); err != nil {
+		return nil, err
+	}
+	id := len(f.groups) + 1
+	group := &client.Group{ID: id, UUID: "group-uuid-" + strconv.Itoa(id), Name: name}
+	f.groups[strconv.Itoa(id)] = group
+	ret := *group
+	return &ret, nil
+}
+
+func (f *fakeTenable) DeleteGroup(_ context.Context, groupId string) error {
+	f.record("DeleteGroup")
+	if err := f.fail("DeleteGroup"); err != nil {
+		return err
+	}
+	if _, ok := f.groups[groupId]; !ok {
+		return notFound("/groups/" + groupId)
+	}
+	delete(f.groups, groupId)
+	delete(f.groupMembers, groupId)
+	return nil
+}
+
+func (f *fakeTenable) GetGroupMembers(_ context.Context, groupId string) ([]client.User, annotations.Annotations, error) {
+	if err := f.fail("GetGroupMembers"); err != nil {
+		return nil, nil, err
+	}
+	if _, ok := f.groups[groupId]; !ok {
+		return nil, nil, notFound("/groups/" + groupId + "/users")
+	}
+	var members []client.User
+	for _, userId := range f.groupMembers[groupId] {
+		if user, ok := f.users[userId]; ok {
+			members = append(members, *user)
+		}
+	}
+	return members, nil, nil
+}
+
+func (f *fakeTenable) CreateUserGroupMembership(_ context.Context, groupId string, userId string, _ bool) error {
+	f.record("CreateUserGroupMembership")
+	if err := f.fail("CreateUserGroupMembership"); err != nil {
+		return err
+	}
+	f.groupMembers[groupId] = append(f.groupMembers[groupId], userId)
+	return nil
+}
+
+func (f *fakeTenable) DeleteUserGroupMembership(_ context.Context, groupId string, userId string) error {
+	f.record("DeleteUserGroupMembership")
+	if err := f.fail("DeleteUserGroupMembership"); err != nil {
+		return err
+	}
+	f.groupMembers[groupId] = slices.DeleteFunc(f.groupMembers[groupId], func(id string) bool {
+		return id == userId
+	})
+	return nil
+}
+
+func (f *fakeTenable) ListPermissions(_ context.Context, _ client.PageOptions) ([]client.Permission, string, annotations.Annotations, error) {
+	if err := f.fail("ListPermissions"); err != nil {
+		return nil, "", nil, err
+	}
+	var permissions []client.Permission
+	for _, permission := range f.permissions {
+		permissions = append(permissions, *permission)
+	}
+	return permissions, "", nil, nil
+}
+
+func (f *fakeTenable) ListTagCategories(_ context.Context, _ client.PageOptions) ([]client.TagCategory, string, annotations.Annotations, error) {
+	if err := f.fail("ListTagCategories"); err != nil {
+		return nil, "", nil, err
+	}
+	return slices.Clone(f.tagCategories), "", nil, nil
+}
+
+func (f *fakeTenable) ListTagValues(_ context.Context, categoryUUID string, _ client.PageOptions) ([]client.TagValue, string, annotations.Annotations, error) {
+	if err := f.fail("ListTagValues"); err != nil {
+		return nil, "", nil, err
+	}
+	var values []client.TagValue
+	for _, value := range f.tagValues {
+		if value.CategoryUUID == categoryUUID {
+			values = append(values, value)
+		}
+	}
+	return values, "", nil, nil
+}
+
+func (f *fakeTenable) GetPermissionDetails(_ context.Context, uuid string) (*client.Permission, error) {
+	if err := f.fail("GetPermissionDetails"); err != nil {
+		return nil, err
+	}
+	permission, ok := f.permissions[uuid]
+	if !ok {
+		return nil, notFound(client.PermissionsPath + "/" + uuid)
+	}
+	ret := *permission
+	ret.Subjects = slices.Clone(permission.Subjects)
+	return &ret, nil
+}
+
+func (f *fakeTenable) UpdatePermission(_ context.Context, updatedPermission *client.Permission) error {
+	f.record("UpdatePermission")
+	if err := f.fail("UpdatePermission"); err != nil {
+		return err
+	}
+	ret := *updatedPermission
+	ret.Subjects = slices.Clone(updatedPermission.Subjects)
+	f.permissions[updatedPermission.UUID.String()] = &ret
+	return nil
+}
+
+func (f *fakeTenable) CreatePermission(_ context.Context, permission *client.Permission) (*client.Permission, error) {
+	f.record("CreatePermission")
+	if err := f.fail("CreatePermission"); err != nil {
+		return nil, err
+	}
+	created := *permission
+	created.UUID = uuid.New()
+	stored := created
+	f.permissions[created.UUID.String()] = &stored
+	return &created, nil
+}
+
+func (f *fakeTenable) DeletePermission(_ context.Context, permissionUUID string) error {
+	f.record("DeletePermission")
+	if err := f.fail("DeletePermission"); err != nil {
+		return err
+	}
+	if _, ok := f.permissions[permissionUUID]; !ok {
+		return notFound(client.PermissionsPath + "/" + permissionUUID)
+	}
+	delete(f.permissions, permissionUUID)
+	return nil
+}
+
+func (f *fakeTenable) ListScans(_ context.Context) ([]client.Scan, error) {
+	if err := f.fail("ListScans"); err != nil {
+		return nil, err
+	}
+	return slices.Clone(f.scans), nil
+}
+
+func (f *fakeTenable) ListPolicies(_ context.Context) ([]client.Policy, error) {
+	if err := f.fail("ListPolicies"); err != nil {
+		return nil, err
+	}
+	return slices.Clone(f.policies), nil
+}
+
+func objectKey(objectType string, objectID int) string {
+	return objectType + ":" + strconv.Itoa(objectID)
+}
+
+func (f *fakeTenable) GetObjectPermissions(_ context.Context, objectType string, objectID int) (*client.ObjectPermissions, error) {
+	if err := f.fail("GetObjectPermissions"); err != nil {
+		return nil, err
+	}
+	return &client.ObjectPermissions{ACLs: slices.Clone(f.objectACLs[objectKey(objectType, objectID)])}, nil
+}
+
+func (f *fakeTenable) UpdateObjectPermissions(_ context.Context, objectType string, objectID int, acls []client.ACL) error {
+	f.record("UpdateObjectPermissions")
+	if err := f.fail("UpdateObjectPermissions"); err != nil {
+		return err
+	}
+	f.objectACLs[objectKey(objectType, objectID)] = slices.Clone(acls)
+	return nil
+}
+
+func (f *fakeTenable) ListCredentials(_ context.Context, _ client.PageOptions) ([]client.ManagedCredential, string, error) {
+	if err := f.fail("ListCredentials"); err != nil {
+		return nil, "", err
+	}
+	var credentials []client.ManagedCredential
+	for _, credential := range f.credentials {
+		credentials = append(credentials, *credential)
+	}
+	return credentials, "", nil
+}
+
+func (f *fakeTenable) GetCredentialDetails(_ context.Context, credentialUUID string) (*client.ManagedCredential, error) {
+	if err := f.fail("GetCredentialDetails"); err != nil {
+		return nil, err
+	}
+	credential, ok := f.credentials[credentialUUID]
+	if !ok {
+		return nil, notFound("/credentials/" + credentialUUID)
+	}
+	ret := *credential
+	ret.Permissions = slices.Clone(credential.Permissions)
+	return &ret, nil
+}
+
+func (f *fakeTenable) UpdateCredentialPermissions(_ context.Context, credentialUUID string, permissions []client.CredentialPermission) error {
+	f.record("UpdateCredentialPermissions")
+	if err := f.fail("UpdateCredentialPermissions"); err != nil {
+		return err
+	}
+	credential, ok := f.credentials[credentialUUID]
+	if !ok {
+		return notFound("/credentials/" + credentialUUID)
+	}
+	credential.Permissions = slices.Clone(permissions)
+	return nil
+}
+
+func newTestResource(resourceType *v2.ResourceType, id string) *v2.Resource {
+	return &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: resourceType.Id, Resource: id},
+		DisplayName: id,
+	}
+}
+
+func newTestEntitlement(resource *v2.Resource, slug string) *v2.Entitlement {
+	return &v2.Entitlement{
+		Id:       resource.Id.ResourceType + ":" + resource.Id.Resource + ":" + slug,
+		Resource: resource,
+		Slug:     slug,
+	}
+}
+
+// provisioningTestCase describes a Grant or Revoke call against a seeded fakeTenable.
+type provisioningTestCase struct {
+	name           string
+	connectorOpts  []Option
+	setup          func(f *fakeTenable)
+	wantAnnotation proto.Message
+	wantErr        error
+	wantCalls      []string
+	// check, when set, asserts on the fake's state after the call.
+	check func(t *testing.T, f *fakeTenable)
+}
+
+func runProvisioningTestCases(
+	t *testing.T,
+	testCases []provisioningTestCase,
+	seed func(f *fakeTenable),
+	call func(ctx context.Context, c *Connector) (annotations.Annotations, error),
+) {
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeTenable()
+			seed(f)
+			if tc.setup != nil {
+				tc.setup(f)
+			}
+
+			c := &Connector{client: f}
+			for _, opt := range tc.connectorOpts {
+				opt(c)
+			}
+			annos, err := call(context.Background(), c)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			if tc.wantAnnotation != nil {
+				require.True(t, annos.Contains(tc.wantAnnotation), "missing %T annotation", tc.wantAnnotation)
+			} else {
+				require.Empty(t, annos)
+			}
+			require.Equal(t, tc.wantCalls, f.calls)
+			if tc.check != nil {
+				tc.check(t, f)
+			}
+		})
+	}
+}
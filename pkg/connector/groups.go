@@ -2,8 +2,10 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 
@@ -20,7 +22,7 @@ import (
 const memberEntitlement = "member"
 
 type groupBuilder struct {
-	client *client.TenableVMClient
+	client client.TenableAPI
 }
 
 func (o *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -173,7 +175,14 @@ func (g *groupBuilder) Revoke(
 
 	members, annos, err := g.client.GetGroupMembers(ctx, groupId)
 	if err != nil {
-		logger.Debug("Failed to add user to group, could not get current memberships: ",
+		if errors.Is(err, client.ErrNotFound) {
+			logger.Debug("Group no longer exists, membership grant already revoked",
+				zap.String("user_id", userId),
+				zap.String("group_id", groupId),
+			)
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+		logger.Debug("Failed to remove user from group, could not get current memberships: ",
 			zap.Error(err),
 			zap.String("user_id", userId),
 			zap.String("group_id", groupId),
@@ -214,7 +223,43 @@ func (g *groupBuilder) Revoke(
 	return nil, nil
 }
 
-func newGroupBuilder(c *client.TenableVMClient) *groupBuilder {
+// Create adds a user group named after the resource's display name.
+func (g *groupBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	name := strings.TrimSpace(resource.GetDisplayName())
+	if name == "" {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: a group name is required")
+	}
+
+	group, err := g.client.CreateGroup(ctx, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: failed to create group %s: %w", name, err)
+	}
+
+	groupResource, err := parseIntoGroupResource(ctx, group, resource.GetParentResourceId())
+	if err != nil {
+		return nil, nil, err
+	}
+	return groupResource, nil, nil
+}
+
+// Delete removes the user group. A group that is already gone counts as deleted.
+func (g *groupBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	logger := ctxzap.Extract(ctx)
+	groupId := resourceId.Resource
+
+	err := g.client.DeleteGroup(ctx, groupId)
+	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			logger.Debug("Group no longer exists, nothing to delete", zap.String("group_id", groupId))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("baton-tenable-vm: failed to delete group %s: %w", groupId, err)
+	}
+
+	return nil, nil
+}
+
+func newGroupBuilder(c client.TenableAPI) *groupBuilder {
 	return &groupBuilder{
 		client: c,
 	}
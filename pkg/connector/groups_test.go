@@ -0,0 +1,144 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+func seedGroups(f *fakeTenable) {
+	f.users["1"] = &client.User{ID: 1, UUID: "user-uuid-1", Name: "Alice"}
+	f.users["2"] = &client.User{ID: 2, UUID: "user-uuid-2", Name: "Bob"}
+	f.groups["10"] = &client.Group{ID: 10, UUID: "group-uuid-10", Name: "Scanners"}
+	f.groupMembers["10"] = []string{"2"}
+}
+
+func TestGroupGrant(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "adds the user to the group",
+			wantCalls: []string{"CreateUserGroupMembership"},
+		},
+		{
+			name:           "user is already a member",
+			setup:          func(f *fakeTenable) { f.groupMembers["10"] = append(f.groupMembers["10"], "1") },
+			wantAnnotation: &v2.GrantAlreadyExists{},
+		},
+		{
+			name:    "listing members fails",
+			setup:   func(f *fakeTenable) { f.failures["GetGroupMembers"] = errAPI },
+			wantErr: errAPI,
+		},
+		{
+			name:      "adding the member fails",
+			setup:     func(f *fakeTenable) { f.failures["CreateUserGroupMembership"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"CreateUserGroupMembership"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedGroups, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		group := newTestResource(groupResourceType, "10")
+		return newGroupBuilder(c.client).Grant(ctx, newTestResource(userResourceType, "1"), newTestEntitlement(group, memberEntitlement))
+	})
+}
+
+func TestGroupRevoke(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "removes the user from the group",
+			wantCalls: []string{"DeleteUserGroupMembership"},
+		},
+		{
+			name:           "user is not a member",
+			setup:          func(f *fakeTenable) { f.groupMembers["10"] = nil },
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:           "group was deleted",
+			setup:          func(f *fakeTenable) { delete(f.groups, "10") },
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:      "removing the member fails",
+			setup:     func(f *fakeTenable) { f.failures["DeleteUserGroupMembership"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"DeleteUserGroupMembership"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedGroups, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		group := newTestResource(groupResourceType, "10")
+		return newGroupBuilder(c.client).Revoke(ctx, &v2.Grant{
+			Principal:   newTestResource(userResourceType, "2"),
+			Entitlement: newTestEntitlement(group, memberEntitlement),
+		})
+	})
+}
+
+func TestGroupCreate(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "creates the group",
+			wantCalls: []string{"CreateGroup"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Len(t, f.groups, 2)
+				require.Equal(t, "Auditors", f.groups["2"].Name)
+			},
+		},
+		{
+			name:      "creating the group fails",
+			setup:     func(f *fakeTenable) { f.failures["CreateGroup"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"CreateGroup"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedGroups, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		resource, annos, err := newGroupBuilder(c.client).Create(ctx, &v2.Resource{
+			Id:          &v2.ResourceId{ResourceType: groupResourceType.Id},
+			DisplayName: "Auditors",
+		})
+		if err == nil {
+			require.Equal(t, "2", resource.Id.Resource)
+			require.Equal(t, "Auditors", resource.DisplayName)
+		}
+		return annos, err
+	})
+}
+
+func TestGroupDelete(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "deletes the group",
+			wantCalls: []string{"DeleteGroup"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.NotContains(t, f.groups, "10")
+			},
+		},
+		{
+			name:      "group was already deleted",
+			setup:     func(f *fakeTenable) { delete(f.groups, "10") },
+			wantCalls: []string{"DeleteGroup"},
+		},
+		{
+			name:      "deleting the group fails",
+			setup:     func(f *fakeTenable) { f.failures["DeleteGroup"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"DeleteGroup"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedGroups, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		return newGroupBuilder(c.client).Delete(ctx, &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "10"})
+	})
+}
@@ -1,60 +1,179 @@
 package connector
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
-	"unicode"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
-	"github.com/conductorone/baton-sdk/pkg/crypto"
 	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
-const symbols = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
-
-func isPasswordValid(password string) bool {
-	var hasUpper, hasLower, hasDigit, hasSpecial bool
-
-	for _, c := range password {
-		switch {
-		case unicode.IsUpper(c):
-			hasUpper = true
-		case unicode.IsLower(c):
-			hasLower = true
-		case unicode.IsDigit(c):
-			hasDigit = true
-		case strings.ContainsRune(symbols, c):
-			hasSpecial = true
+const (
+	upperCaseLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lowerCaseLetters = "abcdefghijklmnopqrstuvwxyz"
+	digits           = "0123456789"
+	symbols          = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+	passwordAlphabet = upperCaseLetters + lowerCaseLetters + digits + symbols
+)
+
+// MinPasswordLength is the shortest password Tenable VM accepts.
+const MinPasswordLength = 12
+
+// MaxPasswordLength is the longest password the connector generates, longer requests are refused.
+const MaxPasswordLength = 128
+
+// passwordClasses are the character classes Tenable VM requires in every password, so every generated password
+// contains each of them at least once.
+var passwordClasses = []struct {
+	name       string
+	characters string
+}{
+	{name: "upper", characters: upperCaseLetters},
+	{name: "lower", characters: lowerCaseLetters},
+	{name: "digit", characters: digits},
+	{name: "symbol", characters: symbols},
+}
+
+// PasswordPolicy describes the passwords generated for new accounts and credential rotations. On top of it,
+// every password mixes upper and lower case letters, digits and symbols, as Tenable VM requires.
+type PasswordPolicy struct {
+	// MinLength is raised to the requested length when a longer password is asked for.
+	MinLength int
+	// ExcludedCharacters are never used, e.g. characters a downstream system cannot handle.
+	ExcludedCharacters string
+}
+
+// DefaultPasswordPolicy returns the policy Tenable VM enforces itself: 12 characters mixing all four classes.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: MinPasswordLength}
+}
+
+// Validate checks that passwords satisfying the policy can be generated and are accepted by Tenable VM.
+func (p PasswordPolicy) Validate() error {
+	if p.MinLength < MinPasswordLength {
+		return fmt.Errorf("baton-tenable-vm: password minimum length %d is below the Tenable VM minimum of %d", p.MinLength, MinPasswordLength)
+	}
+	if p.MinLength > MaxPasswordLength {
+		return fmt.Errorf("baton-tenable-vm: password minimum length %d is above the maximum of %d", p.MinLength, MaxPasswordLength)
+	}
+	for _, class := range passwordClasses {
+		if p.allowed(class.characters) == "" {
+			return fmt.Errorf("baton-tenable-vm: every %s character is excluded from passwords, Tenable VM requires one", class.name)
+		}
+	}
+	return nil
+}
+
+// allowed returns characters without the excluded ones.
+func (p PasswordPolicy) allowed(characters string) string {
+	return strings.Map(func(c rune) rune {
+		if strings.ContainsRune(p.ExcludedCharacters, c) {
+			return -1
 		}
+		return c
+	}, characters)
+}
+
+// describe reports the policy as an annotation on the capability details.
+func (p PasswordPolicy) describe() (*structpb.Struct, error) {
+	classes := make([]any, 0, len(passwordClasses))
+	for _, class := range passwordClasses {
+		classes = append(classes, class.name)
 	}
+	return structpb.NewStruct(map[string]any{
+		"min_length":          p.MinLength,
+		"required_classes":    classes,
+		"excluded_characters": p.ExcludedCharacters,
+	})
+}
 
-	return hasUpper && hasLower && hasDigit && hasSpecial
+func isPasswordValid(password string, policy PasswordPolicy) bool {
+	if len(password) < policy.MinLength || len(password) > MaxPasswordLength || strings.ContainsAny(password, policy.ExcludedCharacters) {
+		return false
+	}
+	for _, class := range passwordClasses {
+		if !strings.ContainsAny(password, class.characters) {
+			return false
+		}
+	}
+	return true
 }
 
-// generateCredentials if the credential option is "Random Password", it returns a randomly generated password.
-func generateCredentials(credentialOptions *v2.CredentialOptions) (string, error) {
+// generateCredentials if the credential option is "Random Password", it returns a randomly generated password of
+// the requested length, or of the policy minimum length when less is requested. Lengths above MaxPasswordLength
+// are refused.
+func generateCredentials(credentialOptions *v2.CredentialOptions, policy PasswordPolicy) (string, error) {
 	if credentialOptions.GetRandomPassword() == nil {
 		return "", errors.New("unsupported credential option")
 	}
+	if err := policy.Validate(); err != nil {
+		return "", err
+	}
 
-	const maxAttempts = 20
-	for i := 0; i < maxAttempts; i++ {
-		password, err := crypto.GenerateRandomPassword(
-			&v2.CredentialOptions_RandomPassword{
-				Length: min(12, credentialOptions.GetRandomPassword().GetLength()),
-			},
-		)
+	requested := credentialOptions.GetRandomPassword().GetLength()
+	if requested > MaxPasswordLength {
+		return "", fmt.Errorf("baton-tenable-vm: requested password length %d is above the maximum of %d", requested, MaxPasswordLength)
+	}
+	length := max(int64(policy.MinLength), requested)
+	password := make([]byte, 0, length)
+
+	// One character of each class first, so the password is valid regardless of the draw.
+	for _, class := range passwordClasses {
+		c, err := randomCharacter(policy.allowed(class.characters))
 		if err != nil {
 			return "", err
 		}
-		if isPasswordValid(password) {
-			return password, nil
+		password = append(password, c)
+	}
+	alphabet := policy.allowed(passwordAlphabet)
+	for int64(len(password)) < length {
+		c, err := randomCharacter(alphabet)
+		if err != nil {
+			return "", err
 		}
+		password = append(password, c)
 	}
 
-	return "", errors.New("failed to generate a valid password after 20 attempts")
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("failed generating password: %w", err)
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	if !isPasswordValid(string(password), policy) {
+		return "", errors.New("failed generating password: the password does not satisfy the password policy")
+	}
+	return string(password), nil
+}
+
+func randomCharacter(characters string) (byte, error) {
+	if characters == "" {
+		return 0, errors.New("failed generating password: no character to pick from")
+	}
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(len(characters))))
+	if err != nil {
+		return 0, fmt.Errorf("failed generating password: %w", err)
+	}
+	return characters[index.Int64()], nil
+}
+
+// entitlementSlug returns the slug ending the entitlement id, or "" when the id is not one of the resource's
+// entitlements. The Slug field is optional and not trusted.
+func entitlementSlug(entitlement *v2.Entitlement) string {
+	resourceId := entitlement.GetResource().GetId()
+	slug, ok := strings.CutPrefix(entitlement.GetId(), resourceId.GetResourceType()+":"+resourceId.GetResource()+":")
+	if !ok {
+		return ""
+	}
+	return slug
 }
 
 func getUserResourceId(uuid string, cachedUsers map[string]*client.User) (*v2.ResourceId, error) {
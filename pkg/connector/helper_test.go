@@ -0,0 +1,99 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCredentials(t *testing.T) {
+	testCases := []struct {
+		name       string
+		policy     PasswordPolicy
+		length     int64
+		wantLength int
+	}{
+		{
+			name:       "honors a longer requested length",
+			policy:     DefaultPasswordPolicy(),
+			length:     32,
+			wantLength: 32,
+		},
+		{
+			name:       "raises a short requested length to the minimum",
+			policy:     DefaultPasswordPolicy(),
+			length:     8,
+			wantLength: MinPasswordLength,
+		},
+		{
+			name:       "uses the policy minimum when no length is requested",
+			policy:     PasswordPolicy{MinLength: 20},
+			wantLength: 20,
+		},
+		{
+			name:       "honors the maximum length",
+			policy:     DefaultPasswordPolicy(),
+			length:     MaxPasswordLength,
+			wantLength: MaxPasswordLength,
+		},
+		{
+			name: "leaves out excluded characters",
+			policy: PasswordPolicy{
+				MinLength:          MinPasswordLength,
+				ExcludedCharacters: "0O1lI\"'`\\" + lowerCaseLetters[1:] + symbols[1:],
+			},
+			length:     64,
+			wantLength: 64,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options := &v2.CredentialOptions{
+				Options: &v2.CredentialOptions_RandomPassword_{
+					RandomPassword: &v2.CredentialOptions_RandomPassword{Length: tc.length},
+				},
+			}
+			for i := 0; i < 50; i++ {
+				password, err := generateCredentials(options, tc.policy)
+				require.NoError(t, err)
+				require.Len(t, password, tc.wantLength)
+				require.False(t, strings.ContainsAny(password, tc.policy.ExcludedCharacters), password)
+				require.True(t, isPasswordValid(password, tc.policy), password)
+			}
+		})
+	}
+}
+
+func TestGenerateCredentialsTooLong(t *testing.T) {
+	options := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{
+			RandomPassword: &v2.CredentialOptions_RandomPassword{Length: MaxPasswordLength + 1},
+		},
+	}
+	_, err := generateCredentials(options, DefaultPasswordPolicy())
+	require.ErrorContains(t, err, "above the maximum")
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	require.NoError(t, DefaultPasswordPolicy().Validate())
+	require.NoError(t, PasswordPolicy{MinLength: 40}.Validate())
+
+	require.Error(t, PasswordPolicy{MinLength: 8}.Validate())
+	require.Error(t, PasswordPolicy{MinLength: MaxPasswordLength + 1}.Validate())
+	// Tenable VM requires every class.
+	require.Error(t, PasswordPolicy{MinLength: 16, ExcludedCharacters: symbols}.Validate())
+	require.Error(t, PasswordPolicy{MinLength: 16, ExcludedCharacters: passwordAlphabet}.Validate())
+}
+
+func TestIsPasswordValid(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	require.True(t, isPasswordValid("Abcdefghij1!", policy))
+	require.False(t, isPasswordValid("Abcdefgh1!", policy))
+	require.False(t, isPasswordValid("abcdefghij1!", policy))
+
+	policy.ExcludedCharacters = "!"
+	require.False(t, isPasswordValid("Abcdefghij1!", policy))
+}
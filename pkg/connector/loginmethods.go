@@ -0,0 +1,238 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// loginMethodsID is the id of the single login_method resource. Its entitlements are the login methods, named
+// after the flags of the user authorizations endpoint it stands for.
+const loginMethodsID = "authorizations"
+
+// loginMethod is one of the ways a user can authenticate to Tenable VM, each switched on and off per user
+// through the user's authorizations.
+type loginMethod struct {
+	id          string
+	displayName string
+	description string
+	permitted   func(a *client.UserAuthorizations) *bool
+}
+
+var loginMethods = []loginMethod{
+	{
+		id:          "api",
+		displayName: "API keys",
+		description: "Authenticate to the Tenable VM API with an access key and secret key",
+		permitted:   func(a *client.UserAuthorizations) *bool { return &a.APIPermitted },
+	},
+	{
+		id:          "password",
+		displayName: "Password",
+		description: "Log in to the Tenable VM user interface with a username and password",
+		permitted:   func(a *client.UserAuthorizations) *bool { return &a.PasswordPermitted },
+	},
+	{
+		id:          "saml",
+		displayName: "SAML",
+		description: "Log in to the Tenable VM user interface through SAML single sign-on",
+		permitted:   func(a *client.UserAuthorizations) *bool { return &a.SAMLPermitted },
+	},
+}
+
+func findLoginMethod(id string) (*loginMethod, error) {
+	for i := range loginMethods {
+		if loginMethods[i].id == id {
+			return &loginMethods[i], nil
+		}
+	}
+	return nil, fmt.Errorf("baton-tenable-vm: unknown login method %s", id)
+}
+
+type loginMethodBuilder struct {
+	client               client.TenableAPI
+	connector            *Connector
+	cachedAuthorizations map[string]*client.UserAuthorizations
+	authorizationsTime   time.Time
+	authorizationsMtx    sync.Mutex
+}
+
+func (o *loginMethodBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return loginMethodResourceType
+}
+
+// List returns the single resource holding the login methods Tenable VM supports.
+func (o *loginMethodBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	resource, err := rs.NewResource(
+		"Login Methods",
+		loginMethodResourceType,
+		loginMethodsID,
+		rs.WithDescription("The ways users can authenticate to Tenable VM"),
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return []*v2.Resource{resource}, "", nil, nil
+}
+
+// Entitlements returns an entitlement per login method.
+func (o *loginMethodBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	entitlements := make([]*v2.Entitlement, 0, len(loginMethods))
+	for _, method := range loginMethods {
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(
+			resource,
+			method.id,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDescription(method.description),
+			entitlement.WithDisplayName(method.displayName),
+		))
+	}
+
+	return entitlements, "", nil, nil
+}
+
+// Grants returns a grant for every login method the authorizations of a user permit.
+func (o *loginMethodBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	allAuthorizations, annos, err := o.loadAuthorizationsCache(ctx)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	userIds := make([]string, 0, len(allAuthorizations))
+	for userId := range allAuthorizations {
+		userIds = append(userIds, userId)
+	}
+	sort.Strings(userIds)
+
+	var grants []*v2.Grant
+	for _, method := range loginMethods {
+		for _, userId := range userIds {
+			if !*method.permitted(allAuthorizations[userId]) {
+				continue
+			}
+			userResourceID := &v2.ResourceId{
+				ResourceType: userResourceType.Id,
+				Resource:     userId,
+			}
+			grants = append(grants, grant.NewGrant(resource, method.id, userResourceID))
+		}
+	}
+	return grants, "", nil, nil
+}
+
+// loadAuthorizationsCache fetches the authorizations of every user once, they are shared by all login methods.
+// The returned map is replaced rather than changed when the cache is reloaded, so it can be read without the lock.
+func (o *loginMethodBuilder) loadAuthorizationsCache(ctx context.Context) (map[string]*client.UserAuthorizations, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	o.authorizationsMtx.Lock()
+	defer o.authorizationsMtx.Unlock()
+
+	if o.cachedAuthorizations != nil && time.Since(o.authorizationsTime) < TTL*time.Minute {
+		return o.cachedAuthorizations, nil, nil
+	}
+
+	users, annos, err := o.connector.cacheUsers(ctx)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to cache users: %w", err)
+	}
+
+	userIds := make([]string, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, strconv.Itoa(user.ID))
+	}
+
+	authorizationsToCache := make(map[string]*client.UserAuthorizations, len(userIds))
+	for _, userId := range userIds {
+		authorizations, err := o.client.GetUserAuthorizations(ctx, userId)
+		if err != nil {
+			if errors.Is(err, client.ErrNotFound) {
+				l.Debug("User deleted while listing authorizations", zap.String("user_id", userId))
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to get authorizations of user %s: %w", userId, err)
+		}
+		authorizationsToCache[userId] = authorizations
+	}
+
+	o.cachedAuthorizations = authorizationsToCache
+	o.authorizationsTime = time.Now()
+	return authorizationsToCache, nil, nil
+}
+
+func (o *loginMethodBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	if principal.Id.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("baton-tenable-vm: login methods can only be granted to users, got %s", principal.Id.ResourceType)
+	}
+	return o.setPermitted(ctx, principal.Id.Resource, entitlementSlug(entitlement), true)
+}
+
+func (o *loginMethodBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	return o.setPermitted(ctx, grant.Principal.Id.Resource, entitlementSlug(grant.Entitlement), false)
+}
+
+// setPermitted flips a single login method of the user, keeping the others as they are.
+func (o *loginMethodBuilder) setPermitted(ctx context.Context, userId string, methodId string, permitted bool) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	method, err := findLoginMethod(methodId)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizations, err := o.client.GetUserAuthorizations(client.WithoutCache(ctx), userId)
+	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			if !permitted {
+				l.Debug("User no longer exists, login method already revoked", zap.String("user_id", userId))
+				return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+			}
+			return nil, fmt.Errorf("baton-tenable-vm: cannot permit login method %s, user %s no longer exists: %w", methodId, userId, err)
+		}
+		return nil, fmt.Errorf("baton-tenable-vm: failed to get authorizations of user %s: %w", userId, err)
+	}
+
+	flag := method.permitted(authorizations)
+	if *flag == permitted {
+		if permitted {
+			return annotations.New(&v2.GrantAlreadyExists{}), nil
+		}
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+	*flag = permitted
+
+	err = o.client.UpdateUserAuthorizations(ctx, userId, *authorizations)
+	if err != nil {
+		return nil, fmt.Errorf("baton-tenable-vm: failed to update authorizations of user %s: %w", userId, err)
+	}
+
+	l.Debug("User login method updated",
+		zap.String("user_id", userId),
+		zap.String("login_method", methodId),
+		zap.Bool("permitted", permitted),
+	)
+
+	return nil, nil
+}
+
+func newLoginMethodBuilder(c client.TenableAPI, conn *Connector) *loginMethodBuilder {
+	return &loginMethodBuilder{
+		client:    c,
+		connector: conn,
+	}
+}
@@ -0,0 +1,130 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+func seedLoginMethods(f *fakeTenable) {
+	seedUsers(f)
+	f.authorizations["1"] = &client.UserAuthorizations{UserUUID: "user-uuid-1", PasswordPermitted: true, SAMLPermitted: true}
+	f.authorizations["2"] = &client.UserAuthorizations{UserUUID: "user-uuid-2", APIPermitted: true, PasswordPermitted: true}
+}
+
+func TestLoginMethodGrants(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedLoginMethods(f)
+	b := newLoginMethodBuilder(f, &Connector{client: f})
+
+	resources, _, _, err := b.List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+
+	entitlements, _, _, err := b.Entitlements(ctx, resources[0], nil)
+	require.NoError(t, err)
+	var slugs []string
+	for _, e := range entitlements {
+		slugs = append(slugs, e.Slug)
+		require.Equal(t, []*v2.ResourceType{userResourceType}, e.GrantableTo)
+	}
+	require.Equal(t, []string{"api", "password", "saml"}, slugs)
+
+	grants, _, _, err := b.Grants(ctx, resources[0], &pagination.Token{})
+	require.NoError(t, err)
+	var got []string
+	for _, g := range grants {
+		got = append(got, entitlementSlug(g.Entitlement)+" -> "+g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource)
+	}
+	require.Equal(t, []string{
+		"api -> user:2",
+		"password -> user:1",
+		"password -> user:2",
+		"saml -> user:1",
+	}, got)
+}
+
+func TestLoginMethodGrant(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "permits api keys",
+			wantCalls: []string{"UpdateUserAuthorizations"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, &client.UserAuthorizations{
+					UserUUID:          "user-uuid-1",
+					APIPermitted:      true,
+					PasswordPermitted: true,
+					SAMLPermitted:     true,
+				}, f.authorizations["1"])
+			},
+		},
+		{
+			name:           "api keys are already permitted",
+			setup:          func(f *fakeTenable) { f.authorizations["1"].APIPermitted = true },
+			wantAnnotation: &v2.GrantAlreadyExists{},
+		},
+		{
+			name:    "user does not exist",
+			setup:   func(f *fakeTenable) { delete(f.users, "1") },
+			wantErr: client.ErrNotFound,
+		},
+		{
+			name:      "updating the authorizations fails",
+			setup:     func(f *fakeTenable) { f.failures["UpdateUserAuthorizations"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"UpdateUserAuthorizations"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedLoginMethods, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		methods := newTestResource(loginMethodResourceType, loginMethodsID)
+		return newLoginMethodBuilder(c.client, c).Grant(ctx, newTestResource(userResourceType, "1"), newTestEntitlement(methods, "api"))
+	})
+}
+
+func TestLoginMethodRevoke(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "forbids password logins and keeps the other methods",
+			wantCalls: []string{"UpdateUserAuthorizations"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, &client.UserAuthorizations{
+					UserUUID:      "user-uuid-1",
+					SAMLPermitted: true,
+				}, f.authorizations["1"])
+			},
+		},
+		{
+			name:           "password logins are already forbidden",
+			setup:          func(f *fakeTenable) { f.authorizations["1"].PasswordPermitted = false },
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:           "user was deleted",
+			setup:          func(f *fakeTenable) { delete(f.users, "1") },
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:    "getting the authorizations fails",
+			setup:   func(f *fakeTenable) { f.failures["GetUserAuthorizations"] = errAPI },
+			wantErr: errAPI,
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedLoginMethods, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		methods := newTestResource(loginMethodResourceType, loginMethodsID)
+		return newLoginMethodBuilder(c.client, c).Revoke(ctx, &v2.Grant{
+			Principal:   newTestResource(userResourceType, "1"),
+			Entitlement: newTestEntitlement(methods, "password"),
+		})
+	})
+}
@@ -0,0 +1,189 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	aclTypeUser = "user"
+	// aclOwnerPermissions is the permission level of the owner entry in a scan or policy ACL.
+	aclOwnerPermissions = 128
+	// credentialCanEdit lets the grantee use, edit and share a managed credential.
+	credentialCanEdit = 64
+)
+
+var (
+	errOwnershipTransferRequired = errors.New("baton-tenable-vm: set transfer-ownership-to to hand over the objects of deleted users")
+	errOwnershipTargetNotFound   = errors.New("baton-tenable-vm: ownership transfer user not found")
+	errOwnershipTargetDeleted    = errors.New("baton-tenable-vm: the ownership transfer user cannot be deleted")
+)
+
+// ownedObjects are the objects Tenable leaves behind or refuses to delete along with their owner.
+type ownedObjects struct {
+	scans       []client.Scan
+	policies    []client.Policy
+	credentials []client.ManagedCredential
+}
+
+func (o *ownedObjects) count() int {
+	return len(o.scans) + len(o.policies) + len(o.credentials)
+}
+
+// listOwnedObjects reads fresh, an object missing from a cached listing would be left behind by the delete.
+func (c *Connector) listOwnedObjects(ctx context.Context, user *client.User) (*ownedObjects, error) {
+	ctx = client.WithoutCache(ctx)
+	var owned ownedObjects
+
+	scans, err := c.client.ListScans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, scan := range scans {
+		if strings.EqualFold(scan.Owner, user.Username) {
+			owned.scans = append(owned.scans, scan)
+		}
+	}
+
+	policies, err := c.client.ListPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		if strings.EqualFold(policy.Owner, user.Username) {
+			owned.policies = append(owned.policies, policy)
+		}
+	}
+
+	var pageToken string
+	for {
+		opts, err := client.ParsePageToken(pageToken, 0)
+		if err != nil {
+			return nil, err
+		}
+		credentials, next, err := c.client.ListCredentials(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, credential := range credentials {
+			if credential.CreatedBy != nil && credential.CreatedBy.ID == user.ID {
+				owned.credentials = append(owned.credentials, credential)
+			}
+		}
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+
+	return &owned, nil
+}
+
+// ownershipTransferTarget looks up the configured transfer-ownership-to user by UUID or username.
+func (c *Connector) ownershipTransferTarget(ctx context.Context) (*client.User, error) {
+	users, _, err := c.cacheUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if user.UUID == c.ownershipTarget || strings.EqualFold(user.Username, c.ownershipTarget) {
+			return user, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errOwnershipTargetNotFound, c.ownershipTarget)
+}
+
+// transferOwnership makes to the owner of every object in owned and returns a report of what was moved.
+func (c *Connector) transferOwnership(ctx context.Context, owned *ownedObjects, from *client.User, to *client.User) (*structpb.Struct, error) {
+	l := ctxzap.Extract(ctx)
+
+	var scans, policies, credentials []any
+	for _, scan := range owned.scans {
+		if err := c.transferObject(ctx, client.ObjectTypeScan, scan.ID, from, to); err != nil {
+			return nil, err
+		}
+		scans = append(scans, strconv.Itoa(scan.ID))
+	}
+	for _, policy := range owned.policies {
+		if err := c.transferObject(ctx, client.ObjectTypePolicy, policy.ID, from, to); err != nil {
+			return nil, err
+		}
+		policies = append(policies, strconv.Itoa(policy.ID))
+	}
+	for _, credential := range owned.credentials {
+		// Written back whole, like the ACLs in transferObject.
+		details, err := c.client.GetCredentialDetails(client.WithoutCache(ctx), credential.UUID)
+		if err != nil {
+			return nil, err
+		}
+		permissions := make([]client.CredentialPermission, 0, len(details.Permissions)+1)
+		for _, permission := range details.Permissions {
+			if permission.GranteeUUID == from.UUID || permission.GranteeUUID == to.UUID {
+				continue
+			}
+			permissions = append(permissions, permission)
+		}
+		permissions = append(permissions, client.CredentialPermission{
+			GranteeUUID: to.UUID,
+			Type:        aclTypeUser,
+			Permissions: credentialCanEdit,
+			Name:        to.Username,
+		})
+		if err := c.client.UpdateCredentialPermissions(ctx, credential.UUID, permissions); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential.UUID)
+	}
+
+	l.Debug("transferred ownership",
+		zap.String("from", from.UUID),
+		zap.String("to", to.UUID),
+		zap.Int("scans", len(scans)),
+		zap.Int("policies", len(policies)),
+		zap.Int("credentials", len(credentials)),
+	)
+
+	return structpb.NewStruct(map[string]any{
+		"transferred_from": from.UUID,
+		"transferred_to":   to.UUID,
+		"scans":            scans,
+		"policies":         policies,
+		"credentials":      credentials,
+	})
+}
+
+// transferObject rewrites the ACL of a scan or policy so to owns it and from is no longer listed. The whole ACL is
+// written back, so it is read fresh rather than from a cached response.
+func (c *Connector) transferObject(ctx context.Context, objectType string, objectID int, from *client.User, to *client.User) error {
+	current, err := c.client.GetObjectPermissions(client.WithoutCache(ctx), objectType, objectID)
+	if err != nil {
+		return err
+	}
+
+	acls := make([]client.ACL, 0, len(current.ACLs)+1)
+	for _, acl := range current.ACLs {
+		if acl.Type == aclTypeUser && (acl.ID == from.ID || acl.ID == to.ID) {
+			continue
+		}
+		acl.Owner = 0
+		acls = append(acls, acl)
+	}
+	acls = append(acls, client.ACL{
+		Type:        aclTypeUser,
+		ID:          to.ID,
+		Name:        to.Username,
+		Owner:       1,
+		Permissions: aclOwnerPermissions,
+	})
+
+	return c.client.UpdateObjectPermissions(ctx, objectType, objectID, acls)
+}
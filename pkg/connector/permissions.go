@@ -2,12 +2,12 @@ package connector
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"slices"
 	"strconv"
 	"strings"
-	"sync"
-	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -19,6 +19,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
@@ -27,15 +29,11 @@ const (
 	subjectTypeGroup    = "UserGroup"
 )
 
+var errActionEntitlement = errors.New("baton-tenable-vm: per-action permission entitlements cannot be granted or revoked, use the assigned entitlement")
+
 type permissionBuilder struct {
-	client              *client.TenableVMClient
-	connector           *Connector
-	permissionsCache    map[string]*client.Permission
-	cacheMutex          sync.Mutex
-	permissionsLastLoad time.Time
-	cachedGroups        map[string]string
-	groupsLastLoad      time.Time
-	groupsMtx           sync.Mutex
+	client    client.TenableAPI
+	connector *Connector
 }
 
 func (o *permissionBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -43,21 +41,27 @@ func (o *permissionBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 }
 
 func (o *permissionBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	annos, err := o.loadPermissionsCache(ctx)
+	pageOpts, err := client.ParsePageToken(pToken.Token, pToken.Size)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	permissions, nextToken, annos, err := o.client.ListPermissions(ctx, pageOpts)
 	if err != nil {
-		return nil, "", annos, fmt.Errorf("failed to load permissions cache: %w", err)
+		return nil, "", annos, fmt.Errorf("failed to list permissions: %w", err)
 	}
 	var resources []*v2.Resource
-	for _, permission := range o.permissionsCache {
-		permissionResource, err := parseIntoPermissionResource(permission, parentResourceID)
+	for _, permission := range permissions {
+		permissionResource, err := parseIntoPermissionResource(&permission, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
 		resources = append(resources, permissionResource)
 	}
-	return resources, "", nil, nil
+	return resources, nextToken, annos, nil
 }
 
+// Entitlements returns the assigned entitlement, which adds or removes a subject, and one entitlement per action
+// of the permission so each action can be reviewed on its own.
 func (o *permissionBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	displayName := fmt.Sprintf("%s permission %s", resource.DisplayName, assignedEntitlement)
 	description := fmt.Sprintf("Permission %s assigned to subject", resource.DisplayName)
@@ -71,56 +75,86 @@ func (o *permissionBuilder) Entitlements(_ context.Context, resource *v2.Resourc
 		),
 	}
 
+	// The actions follow the assigned entitlement and are not grantable on their own.
+	for _, action := range permissionActions(resource) {
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(
+			resource,
+			action,
+			entitlement.WithDescription(fmt.Sprintf("%s on the objects of permission %s", action, resource.DisplayName)),
+			entitlement.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, action)),
+		))
+	}
+
 	return entitlements, "", nil, nil
 }
 
+// permissionActions returns the actions listed in the profile of a synced permission.
+func permissionActions(resource *v2.Resource) []string {
+	permissionTrait, err := rs.GetRoleTrait(resource)
+	if err != nil {
+		return nil
+	}
+	return profileStrings(permissionTrait.GetProfile(), "actions")
+}
+
+// Grants returns a grant of the assigned entitlement and of every action for each subject of the permission. The
+// subjects come from the permissions listed once per sync, only a permission created since is read on its own.
 func (o *permissionBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var grants []*v2.Grant
 	l := ctxzap.Extract(ctx)
 	permissionUUID := resource.Id.Resource
-	annos, err := o.loadPermissionsCache(ctx)
+	permission, annos, err := o.connector.cachedPermission(ctx, permissionUUID)
 	if err != nil {
-		return nil, "", annos, fmt.Errorf("failed to load permissions cache: %w", err)
+		return nil, "", annos, fmt.Errorf("failed to cache permissions: %w", err)
 	}
-	permission, ok := o.permissionsCache[permissionUUID]
-	if !ok {
-		return nil, "", nil, fmt.Errorf("failed to load permission, not found: %w", err)
+	if permission == nil {
+		permission, err = o.client.GetPermissionDetails(ctx, permissionUUID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to get permission details: %w", err)
+		}
 	}
 
-	annos, err = o.connector.cacheUsers(ctx)
+	users, annos, err := o.connector.cacheUsers(ctx)
 	if err != nil {
 		return nil, "", annos, fmt.Errorf("failed to cache users: %w", err)
 	}
 
-	annos, err = o.loadGroupsCache(ctx)
+	groups, annos, err := o.connector.cacheGroups(ctx)
 	if err != nil {
 		return nil, "", annos, fmt.Errorf("failed to cache groups: %w", err)
 	}
 	for _, subject := range permission.Subjects {
-		var newGrant *v2.Grant
+		var (
+			principalID *v2.ResourceId
+			annos       []proto.Message
+			err         error
+		)
 		switch subject.Type {
 		case subjectTypeUser:
-			userResourceID, err := getUserResourceId(subject.UUID.String(), o.connector.cachedUsers)
-			if err != nil {
-				l.Debug("Failed to retrieve user from cache: ", zap.Error(err))
-				return nil, "", nil, err
-			}
-			newGrant = grant.NewGrant(resource, assignedEntitlement, userResourceID)
-			grants = append(grants, newGrant)
+			principalID, err = getUserResourceId(subject.UUID.String(), users)
 		case subjectTypeGroup:
-			groupResourceID, err := getGroupResourceId(subject.UUID.String(), o.cachedGroups)
-			if err != nil {
-				l.Debug("Failed to retrieve user from cache: ", zap.Error(err))
-				return nil, "", nil, err
-			}
-			expandableMsg := &v2.GrantExpandable{
-				EntitlementIds: []string{
-					fmt.Sprintf("group:%s:member", groupResourceID.Resource),
-				},
+			principalID, err = getGroupResourceId(subject.UUID.String(), groups)
+			if err == nil {
+				annos = append(annos, &v2.GrantExpandable{
+					EntitlementIds: []string{
+						fmt.Sprintf("group:%s:member", principalID.Resource),
+					},
+				})
 			}
-			newGrant = grant.NewGrant(resource, assignedEntitlement, groupResourceID,
-				grant.WithAnnotation(expandableMsg, &v2.GrantImmutable{}))
-			grants = append(grants, newGrant)
+		default:
+			continue
+		}
+		if err != nil {
+			// Deleted after the users and groups were cached, leave it out rather than failing the sync.
+			l.Debug("Skipping unknown permission subject", zap.String("permission_uuid", permissionUUID), zap.Error(err))
+			continue
+		}
+		grants = append(grants, grant.NewGrant(resource, assignedEntitlement, principalID, grant.WithAnnotation(annos...)))
+
+		// The actions follow the subject, they change through the assigned entitlement.
+		actionAnnos := append(slices.Clone(annos), &v2.GrantImmutable{})
+		for _, action := range permission.Actions {
+			grants = append(grants, grant.NewGrant(resource, action, principalID, grant.WithAnnotation(actionAnnos...)))
 		}
 	}
 	return grants, "", nil, nil
@@ -158,140 +192,237 @@ func parseIntoPermissionResource(permission *client.Permission, parentResourceID
 	return resource, nil
 }
 
-func (o *permissionBuilder) loadPermissionsCache(ctx context.Context) (annotations.Annotations, error) {
-	o.cacheMutex.Lock()
-	defer o.cacheMutex.Unlock()
-
-	// If already populated and still valid, skip
-	if o.permissionsCache != nil && time.Since(o.permissionsLastLoad) < (TTL*time.Minute) {
-		return nil, nil
-	}
+// getSubject resolves a user or group principal into the subject stored on a permission. The returned error
+// wraps client.ErrNotFound when the principal no longer exists. Principals are read fresh, so one created since
+// the last cached read can be granted and one deleted since is not written to the permission.
+func (o *permissionBuilder) getSubject(ctx context.Context, principal *v2.Resource) (*client.TenableObject, error) {
+	ctx = client.WithoutCache(ctx)
+	switch principal.Id.ResourceType {
+	case userResourceType.Id:
+		user, err := o.client.GetUserDetails(ctx, principal.Id.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user details %w", err)
+		}
+		userUUID, err := uuid.Parse(user.UUID)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing user uuid %w", err)
+		}
+		return &client.TenableObject{Type: subjectTypeUser, UUID: userUUID, Name: user.Name}, nil
 
-	permissions, annos, err := o.client.ListPermissions(ctx)
-	if err != nil {
-		return annos, err
-	}
+	case groupResourceType.Id:
+		groups, _, err := o.client.GetGroups(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list groups %w", err)
+		}
+		for _, group := range groups {
+			if strconv.Itoa(group.ID) != principal.Id.Resource {
+				continue
+			}
+			groupUUID, err := uuid.Parse(group.UUID)
+			if err != nil {
+				return nil, fmt.Errorf("error while parsing group uuid %w", err)
+			}
+			return &client.TenableObject{Type: subjectTypeGroup, UUID: groupUUID, Name: group.Name}, nil
+		}
+		return nil, fmt.Errorf("group %s: %w", principal.Id.Resource, client.ErrNotFound)
 
-	permissionsMap := make(map[string]*client.Permission)
-	for _, permission := range permissions {
-		permissionsMap[permission.UUID.String()] = &permission
+	default:
+		return nil, fmt.Errorf("unsupported principal resource type %s", principal.Id.ResourceType)
 	}
-
-	o.permissionsCache = permissionsMap
-	o.permissionsLastLoad = time.Now()
-	return nil, nil
 }
 
-func (o *permissionBuilder) loadGroupsCache(ctx context.Context) (annotations.Annotations, error) {
-	o.groupsMtx.Lock()
-	defer o.groupsMtx.Unlock()
-
-	if o.cachedGroups != nil && time.Since(o.groupsLastLoad) < TTL*time.Minute {
-		return nil, nil
-	}
-
-	groupsToCache := make(map[string]string)
-	groups, annos, err := o.client.GetGroups(ctx)
-	if err != nil {
-		return annos, fmt.Errorf("error creating users cache %w", err)
-	}
-
-	for _, group := range groups {
-		groupsToCache[group.UUID] = strconv.Itoa(group.ID)
+// checkAssignedEntitlement refuses the per-action entitlements, a subject always gets every action of the
+// permission.
+func checkAssignedEntitlement(entitlement *v2.Entitlement) error {
+	if entitlementSlug(entitlement) != assignedEntitlement {
+		return fmt.Errorf("%w: %s", errActionEntitlement, entitlement.GetId())
 	}
+	return nil
+}
 
-	o.cachedGroups = groupsToCache
-	o.groupsLastLoad = time.Now()
-	return nil, nil
+func hasSubject(permission *client.Permission, subject *client.TenableObject) bool {
+	return slices.ContainsFunc(permission.Subjects, func(obj client.TenableObject) bool {
+		return obj.Type == subject.Type && obj.UUID == subject.UUID
+	})
 }
 
 func (o *permissionBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (
 	annotations.Annotations, error,
 ) {
-	if principal.Id.ResourceType != userResourceType.Id {
-		return nil, fmt.Errorf("can not grant to resource type %s", principal.Id.ResourceType)
+	if err := checkAssignedEntitlement(entitlement); err != nil {
+		return nil, err
 	}
 	permissionUUID := entitlement.Resource.Id.Resource
-	permission, err := o.client.GetPermissionDetails(ctx, permissionUUID)
+	permission, err := o.client.GetPermissionDetails(client.WithoutCache(ctx), permissionUUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get permission details %w", err)
 	}
 
-	user, err := o.client.GetUserDetails(ctx, principal.Id.Resource)
+	subject, err := o.getSubject(ctx, principal)
 	if err != nil {
-		return nil, fmt.Errorf("error while performing grant, failed to get user details %w", err)
-	}
-
-	for _, subject := range permission.Subjects {
-		if subject.UUID.String() == user.UUID {
-			return annotations.New(&v2.GrantAlreadyExists{}), nil
+		if errors.Is(err, client.ErrNotFound) {
+			return nil, fmt.Errorf("error while performing grant, %s %s no longer exists: %w",
+				principal.Id.ResourceType, principal.Id.Resource, err)
 		}
+		return nil, fmt.Errorf("error while performing grant, %w", err)
 	}
 
-	uuid, err := uuid.Parse(user.UUID)
-	if err != nil {
-		return nil, fmt.Errorf("error while parsing user uuid %w", err)
-	}
-
-	tenableSubject := client.TenableObject{
-		Type: subjectTypeUser,
-		Name: user.Name,
-		UUID: uuid,
+	if hasSubject(permission, subject) {
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
 	}
 
-	permission.Subjects = append(permission.Subjects, tenableSubject)
+	permission.Subjects = append(permission.Subjects, *subject)
 	err = o.client.UpdatePermission(ctx, permission)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update permission %w", err)
 	}
+	o.connector.invalidatePermissionsCache()
 
 	return nil, nil
 }
 
 func (o *permissionBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
-	principal := grant.Principal
-	if principal.Id.ResourceType != userResourceType.Id {
-		return nil, fmt.Errorf("can not revoke grant for resource type %s", principal.Id.ResourceType)
+	if err := checkAssignedEntitlement(grant.Entitlement); err != nil {
+		return nil, err
 	}
+	principal := grant.Principal
 	permissionUUID := grant.Entitlement.Resource.Id.Resource
-	permission, err := o.client.GetPermissionDetails(ctx, permissionUUID)
+	permission, err := o.client.GetPermissionDetails(client.WithoutCache(ctx), permissionUUID)
 	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
 		return nil, fmt.Errorf("failed to get permission details %w", err)
 	}
 
-	user, err := o.client.GetUserDetails(ctx, principal.Id.Resource)
+	subject, err := o.getSubject(ctx, principal)
 	if err != nil {
-		return nil, fmt.Errorf("error while revoking grant, failed to get user details %w", err)
-	}
-
-	isGranted := false
-	for _, subject := range permission.Subjects {
-		if subject.UUID.String() == user.UUID {
-			isGranted = true
+		if errors.Is(err, client.ErrNotFound) {
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
 		}
-	}
-	if !isGranted {
-		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		return nil, fmt.Errorf("error while revoking grant, %w", err)
 	}
 
-	uuid, err := uuid.Parse(user.UUID)
-	if err != nil {
-		return nil, fmt.Errorf("error while parsing user uuid %w", err)
+	if !hasSubject(permission, subject) {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
 	}
 
 	permission.Subjects = slices.DeleteFunc(permission.Subjects, func(obj client.TenableObject) bool {
-		return obj.UUID == uuid
+		return obj.Type == subject.Type && obj.UUID == subject.UUID
 	})
 
 	err = o.client.UpdatePermission(ctx, permission)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update permission %w", err)
 	}
+	o.connector.invalidatePermissionsCache()
 
 	return nil, nil
 }
 
-func newPermissionBuilder(cli *client.TenableVMClient, con *Connector) *permissionBuilder {
+// Create adds a permission named after the resource's display name. The role profile holds the actions, as a
+// list or as the space separated string synced permissions carry, and the objects and subjects as lists of
+// {"type", "uuid", "name"} entries, e.g. a single Tag object to scope CanScan to the assets tagged Env:Prod.
+func (o *permissionBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	name := strings.TrimSpace(resource.GetDisplayName())
+	if name == "" {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: a permission name is required")
+	}
+
+	var profile *structpb.Struct
+	if permissionTrait, err := rs.GetRoleTrait(resource); err == nil {
+		profile = permissionTrait.GetProfile()
+	}
+	actions := profileStrings(profile, "actions")
+	if len(actions) == 0 {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: permission %s needs at least one action", name)
+	}
+	objects, err := profileTenableObjects(profile, "objects")
+	if err != nil {
+		return nil, nil, err
+	}
+	subjects, err := profileTenableObjects(profile, "subjects")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	permission, err := o.client.CreatePermission(ctx, &client.Permission{
+		Name:     name,
+		Actions:  actions,
+		Objects:  objects,
+		Subjects: subjects,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: failed to create permission %s: %w", name, err)
+	}
+	o.connector.invalidatePermissionsCache()
+
+	permissionResource, err := parseIntoPermissionResource(permission, resource.GetParentResourceId())
+	if err != nil {
+		return nil, nil, err
+	}
+	return permissionResource, nil, nil
+}
+
+// Delete removes the permission. A permission that is already gone counts as deleted.
+func (o *permissionBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	permissionUUID := resourceId.Resource
+
+	err := o.client.DeletePermission(ctx, permissionUUID)
+	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			l.Debug("Permission no longer exists, nothing to delete", zap.String("permission_uuid", permissionUUID))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("baton-tenable-vm: failed to delete permission %s: %w", permissionUUID, err)
+	}
+	o.connector.invalidatePermissionsCache()
+
+	return nil, nil
+}
+
+// profileStrings reads a list of strings from the profile, given either as a list or as a string separated by
+// spaces or commas.
+func profileStrings(profile *structpb.Struct, key string) []string {
+	value := profile.GetFields()[key]
+	if list := value.GetListValue(); list != nil {
+		var values []string
+		for _, item := range list.GetValues() {
+			if item := strings.TrimSpace(item.GetStringValue()); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values
+	}
+	return strings.FieldsFunc(value.GetStringValue(), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// profileTenableObjects reads a list of {"type", "uuid", "name"} entries from the profile.
+func profileTenableObjects(profile *structpb.Struct, key string) ([]client.TenableObject, error) {
+	value, ok := profile.GetFields()[key]
+	if !ok {
+		return nil, nil
+	}
+	raw, err := value.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("baton-tenable-vm: invalid permission %s: %w", key, err)
+	}
+	var objects []client.TenableObject
+	if err := json.Unmarshal(raw, &objects); err != nil {
+		return nil, fmt.Errorf("baton-tenable-vm: permission %s must be a list of objects with a type, uuid and name: %w", key, err)
+	}
+	for _, obj := range objects {
+		if obj.Type == "" {
+			return nil, fmt.Errorf("baton-tenable-vm: every permission %s entry needs a type", key)
+		}
+	}
+	return objects, nil
+}
+
+func newPermissionBuilder(cli client.TenableAPI, con *Connector) *permissionBuilder {
 	return &permissionBuilder{
 		client:    cli,
 		connector: con,
@@ -0,0 +1,417 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var (
+	testPermissionUUID = uuid.MustParse("7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0001")
+	testUserUUID       = uuid.MustParse("7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0101")
+)
+
+func seedPermissions(f *fakeTenable) {
+	f.users["1"] = &client.User{ID: 1, UUID: testUserUUID.String(), Name: "Alice"}
+	f.permissions[testPermissionUUID.String()] = &client.Permission{
+		UUID:    testPermissionUUID,
+		Name:    "Scan production",
+		Actions: []string{"CanView", "CanScan"},
+		Objects: []client.TenableObject{{Type: "Tag", Name: "Env:Prod"}},
+	}
+}
+
+func withUserSubject(f *fakeTenable) {
+	permission := f.permissions[testPermissionUUID.String()]
+	permission.Subjects = append(permission.Subjects, client.TenableObject{Type: subjectTypeUser, UUID: testUserUUID, Name: "Alice"})
+}
+
+func TestPermissionGrant(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "adds the user as a subject",
+			wantCalls: []string{"UpdatePermission"},
+		},
+		{
+			name:           "user is already a subject",
+			setup:          withUserSubject,
+			wantAnnotation: &v2.GrantAlreadyExists{},
+		},
+		{
+			name:    "permission was deleted",
+			setup:   func(f *fakeTenable) { delete(f.permissions, testPermissionUUID.String()) },
+			wantErr: client.ErrNotFound,
+		},
+		{
+			name:      "updating the permission fails",
+			setup:     func(f *fakeTenable) { f.failures["UpdatePermission"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"UpdatePermission"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedPermissions, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		permission := newTestResource(permissionResourceType, testPermissionUUID.String())
+		return newPermissionBuilder(c.client, c).Grant(ctx, newTestResource(userResourceType, "1"), newTestEntitlement(permission, assignedEntitlement))
+	})
+}
+
+func TestPermissionRevoke(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "removes the user from the subjects",
+			setup:     withUserSubject,
+			wantCalls: []string{"UpdatePermission"},
+		},
+		{
+			name:           "user is not a subject",
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:           "user was deleted",
+			setup:          func(f *fakeTenable) { delete(f.users, "1") },
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:           "permission was deleted",
+			setup:          func(f *fakeTenable) { delete(f.permissions, testPermissionUUID.String()) },
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name: "updating the permission fails",
+			setup: func(f *fakeTenable) {
+				withUserSubject(f)
+				f.failures["UpdatePermission"] = errAPI
+			},
+			wantErr:   errAPI,
+			wantCalls: []string{"UpdatePermission"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedPermissions, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		permission := newTestResource(permissionResourceType, testPermissionUUID.String())
+		return newPermissionBuilder(c.client, c).Revoke(ctx, &v2.Grant{
+			Principal:   newTestResource(userResourceType, "1"),
+			Entitlement: newTestEntitlement(permission, assignedEntitlement),
+		})
+	})
+}
+
+var testGroupUUID = uuid.MustParse("7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0201")
+
+func seedGroupPermissions(f *fakeTenable) {
+	seedPermissions(f)
+	f.groups["10"] = &client.Group{ID: 10, UUID: testGroupUUID.String(), Name: "Ops"}
+}
+
+func withGroupSubject(f *fakeTenable) {
+	permission := f.permissions[testPermissionUUID.String()]
+	permission.Subjects = append(permission.Subjects, client.TenableObject{Type: subjectTypeGroup, UUID: testGroupUUID, Name: "Ops"})
+}
+
+func TestPermissionGroupGrant(t *testing.T) {
+	testCases := []provisioningTestCase{
+		{
+			name:      "adds the group as a subject",
+			setup:     withUserSubject,
+			wantCalls: []string{"UpdatePermission"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, []client.TenableObject{
+					{Type: subjectTypeUser, UUID: testUserUUID, Name: "Alice"},
+					{Type: subjectTypeGroup, UUID: testGroupUUID, Name: "Ops"},
+				}, f.permissions[testPermissionUUID.String()].Subjects)
+			},
+		},
+		{
+			name:           "group is already a subject",
+			setup:          withGroupSubject,
+			wantAnnotation: &v2.GrantAlreadyExists{},
+		},
+		{
+			name:    "group was deleted",
+			setup:   func(f *fakeTenable) { delete(f.groups, "10") },
+			wantErr: client.ErrNotFound,
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedGroupPermissions, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		permission := newTestResource(permissionResourceType, testPermissionUUID.String())
+		return newPermissionBuilder(c.client, c).Grant(ctx, newTestResource(groupResourceType, "10"), newTestEntitlement(permission, assignedEntitlement))
+	})
+}
+
+func TestPermissionGroupRevoke(t *testing.T) {
+	testCases := []provisioningTestCase{
+		{
+			name: "removes only the group from the subjects",
+			setup: func(f *fakeTenable) {
+				withUserSubject(f)
+				withGroupSubject(f)
+			},
+			wantCalls: []string{"UpdatePermission"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, []client.TenableObject{
+					{Type: subjectTypeUser, UUID: testUserUUID, Name: "Alice"},
+				}, f.permissions[testPermissionUUID.String()].Subjects)
+			},
+		},
+		{
+			name:           "group is not a subject",
+			setup:          withUserSubject,
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name: "group was deleted",
+			setup: func(f *fakeTenable) {
+				withGroupSubject(f)
+				delete(f.groups, "10")
+			},
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedGroupPermissions, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		permission := newTestResource(permissionResourceType, testPermissionUUID.String())
+		return newPermissionBuilder(c.client, c).Revoke(ctx, &v2.Grant{
+			Principal:   newTestResource(groupResourceType, "10"),
+			Entitlement: newTestEntitlement(permission, assignedEntitlement),
+		})
+	})
+}
+
+func TestPermissionGroupGrants(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedGroupPermissions(f)
+	withGroupSubject(f)
+	// A group deleted after the permission was last written is left out.
+	permission := f.permissions[testPermissionUUID.String()]
+	permission.Subjects = append(permission.Subjects, client.TenableObject{
+		Type: subjectTypeGroup,
+		UUID: uuid.MustParse("7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0299"),
+		Name: "Former",
+	})
+	b := newPermissionBuilder(f, &Connector{client: f})
+
+	resource, err := parseIntoPermissionResource(f.permissions[testPermissionUUID.String()], nil)
+	require.NoError(t, err)
+
+	grants, _, _, err := b.Grants(ctx, resource, nil)
+	require.NoError(t, err)
+	require.Len(t, grants, 3)
+	for _, g := range grants {
+		require.Equal(t, "10", g.Principal.Id.Resource)
+		annos := annotations.Annotations(g.Annotations)
+		require.True(t, annos.Contains(&v2.GrantExpandable{}), g.Entitlement.Id)
+		// The assigned grant can be revoked, only the actions following it cannot.
+		require.Equal(t, !strings.HasSuffix(g.Entitlement.Id, ":"+assignedEntitlement), annos.Contains(&v2.GrantImmutable{}), g.Entitlement.Id)
+	}
+}
+
+func TestPermissionGrantsFromListing(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedPermissions(f)
+	withUserSubject(f)
+	b := newPermissionBuilder(f, &Connector{client: f})
+
+	resource, err := parseIntoPermissionResource(f.permissions[testPermissionUUID.String()], nil)
+	require.NoError(t, err)
+
+	// The subjects come from the listing, not from a read per permission.
+	f.failures["GetPermissionDetails"] = errors.New("api down")
+	grants, _, _, err := b.Grants(ctx, resource, nil)
+	require.NoError(t, err)
+	require.Len(t, grants, 3)
+
+	// Created after the permissions were listed, so read on its own.
+	delete(f.failures, "GetPermissionDetails")
+	created := &client.Permission{
+		UUID:     uuid.MustParse("7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0003"),
+		Name:     "View production",
+		Actions:  []string{"CanView"},
+		Subjects: []client.TenableObject{{Type: subjectTypeUser, UUID: testUserUUID, Name: "Alice"}},
+	}
+	f.permissions[created.UUID.String()] = created
+	resource, err = parseIntoPermissionResource(created, nil)
+	require.NoError(t, err)
+	grants, _, _, err = b.Grants(ctx, resource, nil)
+	require.NoError(t, err)
+	require.Len(t, grants, 2)
+}
+
+func TestPermissionCreate(t *testing.T) {
+	errAPI := errors.New("api down")
+	tagUUID := uuid.MustParse("7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0201")
+	testCases := []struct {
+		provisioningTestCase
+		profile map[string]any
+		// wantInvalid is set when the profile is rejected before calling Tenable.
+		wantInvalid bool
+	}{
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "creates the permission",
+				wantCalls: []string{"CreatePermission"},
+				check: func(t *testing.T, f *fakeTenable) {
+					require.Len(t, f.permissions, 2)
+					for id, permission := range f.permissions {
+						if id == testPermissionUUID.String() {
+							continue
+						}
+						require.Equal(t, "Scan staging", permission.Name)
+						require.Equal(t, []string{"CanView", "CanScan"}, permission.Actions)
+						require.Equal(t, []client.TenableObject{{Type: "Tag", UUID: tagUUID, Name: "Env:Staging"}}, permission.Objects)
+						require.Equal(t, []client.TenableObject{{Type: subjectTypeUser, UUID: testUserUUID, Name: "Alice"}}, permission.Subjects)
+					}
+				},
+			},
+			profile: map[string]any{
+				"actions":  []any{"CanView", "CanScan"},
+				"objects":  []any{map[string]any{"type": "Tag", "uuid": tagUUID.String(), "name": "Env:Staging"}},
+				"subjects": []any{map[string]any{"type": subjectTypeUser, "uuid": testUserUUID.String(), "name": "Alice"}},
+			},
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "actions as a string",
+				wantCalls: []string{"CreatePermission"},
+			},
+			profile: map[string]any{"actions": "CanView CanScan"},
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name: "no actions",
+			},
+			profile:     map[string]any{},
+			wantInvalid: true,
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name: "objects are not a list of objects",
+			},
+			profile:     map[string]any{"actions": "CanView", "objects": "Env:Staging"},
+			wantInvalid: true,
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "creating the permission fails",
+				setup:     func(f *fakeTenable) { f.failures["CreatePermission"] = errAPI },
+				wantErr:   errAPI,
+				wantCalls: []string{"CreatePermission"},
+			},
+			profile: map[string]any{"actions": "CanView"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeTenable()
+			seedPermissions(f)
+			if tc.setup != nil {
+				tc.setup(f)
+			}
+
+			profile, err := structpb.NewStruct(tc.profile)
+			require.NoError(t, err)
+			resource, _, err := newPermissionBuilder(f, &Connector{client: f}).Create(context.Background(), &v2.Resource{
+				Id:          &v2.ResourceId{ResourceType: permissionResourceType.Id},
+				DisplayName: "Scan staging",
+				Annotations: annotations.New(&v2.RoleTrait{Profile: profile}),
+			})
+			require.Equal(t, tc.wantCalls, f.calls)
+			switch {
+			case tc.wantErr != nil:
+				require.ErrorIs(t, err, tc.wantErr)
+			case tc.wantInvalid:
+				require.Error(t, err)
+			default:
+				require.NoError(t, err)
+				require.Contains(t, f.permissions, resource.Id.Resource)
+				require.Equal(t, "Scan staging", resource.DisplayName)
+			}
+			if tc.check != nil {
+				tc.check(t, f)
+			}
+		})
+	}
+}
+
+func TestPermissionDelete(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "deletes the permission",
+			wantCalls: []string{"DeletePermission"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Empty(t, f.permissions)
+			},
+		},
+		{
+			name:      "permission was already deleted",
+			setup:     func(f *fakeTenable) { delete(f.permissions, testPermissionUUID.String()) },
+			wantCalls: []string{"DeletePermission"},
+		},
+		{
+			name:      "deleting the permission fails",
+			setup:     func(f *fakeTenable) { f.failures["DeletePermission"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"DeletePermission"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedPermissions, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		return newPermissionBuilder(c.client, c).Delete(ctx, &v2.ResourceId{ResourceType: permissionResourceType.Id, Resource: testPermissionUUID.String()})
+	})
+}
+
+func TestPermissionActionEntitlements(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedPermissions(f)
+	withUserSubject(f)
+	b := newPermissionBuilder(f, &Connector{client: f})
+
+	resource, err := parseIntoPermissionResource(f.permissions[testPermissionUUID.String()], nil)
+	require.NoError(t, err)
+
+	entitlements, _, _, err := b.Entitlements(ctx, resource, nil)
+	require.NoError(t, err)
+	var slugs []string
+	for _, e := range entitlements {
+		slugs = append(slugs, e.Slug)
+		// Only the assigned entitlement can be provisioned.
+		require.Equal(t, e.Slug == assignedEntitlement, len(e.GrantableTo) > 0, e.Slug)
+	}
+	require.Equal(t, []string{assignedEntitlement, "CanView", "CanScan"}, slugs)
+
+	grants, _, _, err := b.Grants(ctx, resource, nil)
+	require.NoError(t, err)
+	require.Len(t, grants, 3)
+	for _, g := range grants {
+		require.Equal(t, "1", g.Principal.Id.Resource)
+		annos := annotations.Annotations(g.Annotations)
+		require.Equal(t, !strings.HasSuffix(g.Entitlement.Id, ":"+assignedEntitlement), annos.Contains(&v2.GrantImmutable{}), g.Entitlement.Id)
+	}
+
+	_, err = b.Grant(ctx, newTestResource(userResourceType, "1"), newTestEntitlement(resource, "CanScan"))
+	require.ErrorIs(t, err, errActionEntitlement)
+	_, err = b.Revoke(ctx, &v2.Grant{Principal: newTestResource(userResourceType, "1"), Entitlement: newTestEntitlement(resource, "CanScan")})
+	require.ErrorIs(t, err, errActionEntitlement)
+	// The slug is read from the id, the optional Slug field may be missing.
+	withoutSlug := newTestEntitlement(resource, "CanScan")
+	withoutSlug.Slug = ""
+	_, err = b.Grant(ctx, newTestResource(userResourceType, "1"), withoutSlug)
+	require.ErrorIs(t, err, errActionEntitlement)
+	require.Empty(t, f.calls)
+}
@@ -28,3 +28,29 @@ var permissionResourceType = &v2.ResourceType{
 	DisplayName: "Permission",
 	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
 }
+
+var loginMethodResourceType = &v2.ResourceType{
+	Id:          "login_method",
+	DisplayName: "Login Method",
+}
+
+var userTypeResourceType = &v2.ResourceType{
+	Id:          "user_type",
+	DisplayName: "User Type",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
+}
+
+var capabilityResourceType = &v2.ResourceType{
+	Id:          "capability",
+	DisplayName: "Capability",
+}
+
+var tagCategoryResourceType = &v2.ResourceType{
+	Id:          "tag_category",
+	DisplayName: "Tag Category",
+}
+
+var tagValueResourceType = &v2.ResourceType{
+	Id:          "tag_value",
+	DisplayName: "Tag Value",
+}
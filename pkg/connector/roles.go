@@ -2,6 +2,7 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strconv"
@@ -19,15 +20,27 @@ import (
 	"github.com/conductorone/baton-tenable-vm/pkg/client"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
-	rolePermissionName = "assigned"
-	BasicUserRole      = 16
+	rolePermissionName    = "assigned"
+	BasicUserRole         = 16
+	AdministratorUserRole = 64
+	basicRoleName         = "Basic"
+)
+
+// customRoleType is the RoleDetails.Type of roles created in the tenant, every other type is built in.
+const customRoleType = "CUSTOM"
+
+var (
+	errBasicRoleOnly = errors.New("baton-tenable-vm: cannot revoke the basic role from a user holding no other role")
+	errBuiltInRole   = errors.New("baton-tenable-vm: built-in roles cannot be changed")
+	errRoleAssigned  = errors.New("baton-tenable-vm: cannot delete a role still assigned to users, set force-delete-assigned-roles to remove it from them first")
 )
 
 type roleBuilder struct {
-	client        *client.TenableVMClient
+	client        client.TenableAPI
 	connector     *Connector
 	roleCache     map[string]RoleMapRegistry
 	cacheMutex    sync.Mutex
@@ -116,12 +129,11 @@ func (o *roleBuilder) loadRoleMapCache(ctx context.Context) (annotations.Annotat
 		return nil, nil
 	}
 
-	annos, err := o.connector.cacheUsers(ctx)
+	cachedUsers, annos, err := o.connector.cacheUsers(ctx)
 	if err != nil {
 		return annos, fmt.Errorf("failed to cache users: %w", err)
 	}
 
-	cachedUsers := o.connector.cachedUsers
 	roleMap := make(map[string]RoleMapRegistry)
 	for _, user := range cachedUsers {
 		for _, role := range user.RbacRoles {
@@ -182,9 +194,12 @@ func (rb *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitl
 	user, err := rb.client.GetUserDetails(ctx, userId)
 	if err != nil {
 		l.Debug("Error while getting user details", zap.Error(err))
+		if errors.Is(err, client.ErrNotFound) {
+			return nil, fmt.Errorf("baton-tenable-vm: cannot grant role %s, user %s no longer exists: %w", roleId, userId, err)
+		}
 		return nil, err
 	}
-	userRoles, err := rb.client.GetUserRoles(ctx, user.UUID)
+	userRoles, err := rb.client.GetUserRoles(client.WithoutCache(ctx), user.UUID)
 
 	if err != nil {
 		l.Debug("Error while getting user roles", zap.Error(err))
@@ -195,7 +210,9 @@ func (rb *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitl
 		return annotations.New(&v2.GrantAlreadyExists{}), nil
 	}
 
-	_, err = rb.client.UpdateUserRoles(ctx, user.UUID, roleId)
+	// The endpoint replaces the whole role set, so send the user's current roles along with the new one.
+	roleUUIDs := append(slices.Clone(userRoles.RolesUUID), roleId)
+	_, err = rb.client.UpdateUserRoles(ctx, user.UUID, roleUUIDs)
 	if err != nil {
 		l.Debug("Error while updating user role",
 			zap.String("role id", roleId),
@@ -216,44 +233,228 @@ func (rb *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (
 	roleId := grant.Entitlement.Resource.Id.Resource
 	user, err := rb.client.GetUserDetails(ctx, userId)
 	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			l.Debug("User no longer exists, role grant already revoked", zap.String("user id", userId))
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
 		l.Debug("Error while getting user details", zap.Error(err))
 		return nil, err
 	}
-	userRoles, err := rb.client.GetUserRoles(ctx, user.UUID)
+	removed, err := rb.removeRole(ctx, user, roleId)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	return nil, nil
+}
+
+// removeRole takes roleId away from the user, falling back to the built-in basic role when it was the user's
+// only role. It reports false when the user did not hold the role.
+func (rb *roleBuilder) removeRole(ctx context.Context, user *client.User, roleId string) (bool, error) {
+	l := ctxzap.Extract(ctx)
+	userRoles, err := rb.client.GetUserRoles(client.WithoutCache(ctx), user.UUID)
 
 	if err != nil {
 		l.Debug("Error while getting user roles", zap.Error(err))
-		return nil, err
+		return false, err
 	}
 
 	if !slices.Contains(userRoles.RolesUUID, roleId) {
-		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		return false, nil
 	}
 
-	updateUser := client.UserUpdateReqBody{
-		Permissions: BasicUserRole,
+	roleUUIDs := slices.DeleteFunc(slices.Clone(userRoles.RolesUUID), func(id string) bool {
+		return id == roleId
+	})
+	if len(roleUUIDs) == 0 {
+		// Tenable users always hold at least one role, fall back to the built-in basic role.
+		basicRoleUUID, err := rb.basicRoleUUID(ctx)
+		if err != nil {
+			return false, err
+		}
+		if basicRoleUUID == roleId {
+			return false, fmt.Errorf("%w: user %d", errBasicRoleOnly, user.ID)
+		}
+		roleUUIDs = []string{basicRoleUUID}
 	}
 
-	updatedUser, err := rb.client.UpdateUser(ctx, userId, updateUser)
+	updatedRoles, err := rb.client.UpdateUserRoles(ctx, user.UUID, roleUUIDs)
 	if err != nil {
 		l.Debug("Error while updating user role",
 			zap.String("role id", roleId),
 			zap.Any("user uuid", user.UUID),
 			zap.Error(err))
-		return nil, err
+		return false, err
 	}
 
-	l.Debug("User updated successfully",
-		zap.String("Name", updatedUser.Name),
-		zap.String("Email", updatedUser.Email),
-		zap.Int("Permissions", updatedUser.Permissions),
-		zap.Bool("Name", updatedUser.Enabled),
+	l.Debug("User roles updated successfully",
+		zap.String("user uuid", user.UUID),
+		zap.Strings("roles", updatedRoles.RolesUUID),
 	)
 
+	return true, nil
+}
+
+// Create adds a custom role named after the resource's display name, with the description and the permission
+// strings held in the role profile. When a custom role with that name already exists it is updated to match
+// instead, so roles defined as code can be applied repeatedly.
+func (rb *roleBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	name := strings.TrimSpace(resource.GetDisplayName())
+	if name == "" {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: a role name is required")
+	}
+	permissions, err := rolePermissionStrings(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	body := client.RoleReqBody{
+		Name:        name,
+		Description: resource.GetDescription(),
+		Permissions: permissions,
+	}
+
+	roles, _, err := rb.client.GetRoles(client.WithoutCache(ctx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: failed to list roles: %w", err)
+	}
+	idx := slices.IndexFunc(roles, func(role *client.RoleDetails) bool { return strings.EqualFold(role.Name, name) })
+
+	var role *client.RoleDetails
+	switch {
+	case idx < 0:
+		role, err = rb.client.CreateRole(ctx, body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("baton-tenable-vm: failed to create role %s: %w", name, err)
+		}
+	case roles[idx].Type != customRoleType:
+		return nil, nil, fmt.Errorf("%w: %s is a %s role", errBuiltInRole, roles[idx].Name, roles[idx].Type)
+	default:
+		existing := roles[idx]
+		roleId := existing.UUID.String()
+		role = existing
+		if existing.Description != body.Description || !slices.Equal(existing.Permissions, body.Permissions) {
+			role, err = rb.client.UpdateRole(ctx, roleId, body)
+			if err != nil {
+				return nil, nil, fmt.Errorf("baton-tenable-vm: failed to update role %s: %w", roleId, err)
+			}
+		}
+		l.Debug("Role already exists", zap.String("role_id", roleId), zap.Bool("updated", role != existing))
+	}
+
+	roleResource, err := parseIntoRoleResource(role, resource.GetParentResourceId())
+	if err != nil {
+		return nil, nil, err
+	}
+	return roleResource, nil, nil
+}
+
+// Delete removes a custom role. Built-in roles are never deleted, and roles still assigned to users are only
+// deleted with force-delete-assigned-roles, after taking the role away from those users.
+func (rb *roleBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	roleId := resourceId.Resource
+
+	roles, _, err := rb.client.GetRoles(client.WithoutCache(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("baton-tenable-vm: failed to list roles: %w", err)
+	}
+	idx := slices.IndexFunc(roles, func(role *client.RoleDetails) bool { return role.UUID.String() == roleId })
+	if idx < 0 {
+		l.Debug("Role no longer exists, nothing to delete", zap.String("role_id", roleId))
+		return nil, nil
+	}
+	if roles[idx].Type != customRoleType {
+		return nil, fmt.Errorf("%w: %s is a %s role", errBuiltInRole, roles[idx].Name, roles[idx].Type)
+	}
+
+	// Read the assignments uncached, a cached list could miss a user given the role since.
+	users, annos, err := rb.client.GetUsers(client.WithoutCache(ctx))
+	if err != nil {
+		return annos, fmt.Errorf("baton-tenable-vm: failed to list users: %w", err)
+	}
+	var assigned []*client.User
+	for _, user := range users {
+		if slices.ContainsFunc(user.RbacRoles, func(role client.Role) bool { return role.UUID.String() == roleId }) {
+			assigned = append(assigned, &user)
+		}
+	}
+	if len(assigned) > 0 && !rb.connector.forceRoleDeletion {
+		return nil, fmt.Errorf("%w: role %s is assigned to %d users", errRoleAssigned, roleId, len(assigned))
+	}
+	for _, user := range assigned {
+		if _, err := rb.removeRole(ctx, user, roleId); err != nil {
+			return nil, fmt.Errorf("baton-tenable-vm: failed to remove role %s from user %d: %w", roleId, user.ID, err)
+		}
+	}
+
+	err = rb.client.DeleteRole(ctx, roleId)
+	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			l.Debug("Role no longer exists, nothing to delete", zap.String("role_id", roleId))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("baton-tenable-vm: failed to delete role %s: %w", roleId, err)
+	}
+	if len(assigned) > 0 {
+		rb.connector.invalidateUsersCache()
+		rb.cacheMutex.Lock()
+		rb.roleCache = nil
+		rb.cacheMutex.Unlock()
+	}
+
 	return nil, nil
 }
 
-func newRoleBuilder(c *client.TenableVMClient, conn *Connector) *roleBuilder {
+// rolePermissionStrings reads the permission strings of a role to create from its profile, either as a list or
+// as the comma separated string synced roles carry.
+func rolePermissionStrings(resource *v2.Resource) ([]string, error) {
+	roleTrait, err := rs.GetRoleTrait(resource)
+	if err != nil {
+		// A role without a profile is created without permission strings.
+		return []string{}, nil
+	}
+
+	permissions := []string{}
+	value, ok := rs.GetProfileStringValue(roleTrait.GetProfile(), "permissions")
+	if ok {
+		for _, permission := range strings.Split(value, ",") {
+			if permission = strings.TrimSpace(permission); permission != "" {
+				permissions = append(permissions, permission)
+			}
+		}
+		return permissions, nil
+	}
+
+	list := roleTrait.GetProfile().GetFields()["permissions"].GetListValue()
+	for _, item := range list.GetValues() {
+		permission, ok := item.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return nil, fmt.Errorf("baton-tenable-vm: role permission strings must be strings, got %v", item.AsInterface())
+		}
+		permissions = append(permissions, permission.StringValue)
+	}
+	return permissions, nil
+}
+
+// basicRoleUUID returns the uuid of the built-in basic role, assigned to users left without any role.
+func (rb *roleBuilder) basicRoleUUID(ctx context.Context) (string, error) {
+	roles, _, err := rb.client.GetRoles(ctx)
+	if err != nil {
+		return "", fmt.Errorf("baton-tenable-vm: failed to list roles: %w", err)
+	}
+	for _, role := range roles {
+		if strings.EqualFold(role.Name, basicRoleName) {
+			return role.UUID.String(), nil
+		}
+	}
+	return "", fmt.Errorf("baton-tenable-vm: the built-in %s role was not found", basicRoleName)
+}
+
+func newRoleBuilder(c client.TenableAPI, conn *Connector) *roleBuilder {
 	return &roleBuilder{
 		client:    c,
 		connector: conn,
@@ -0,0 +1,308 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	testRoleUUID  = "1b3f2a3c-8e1e-4c5a-9d55-8a0c1c7e0001"
+	otherRoleUUID = "1b3f2a3c-8e1e-4c5a-9d55-8a0c1c7e0002"
+	basicRoleUUID = "1b3f2a3c-8e1e-4c5a-9d55-8a0c1c7e0003"
+)
+
+func seedRoles(f *fakeTenable) {
+	f.users["1"] = &client.User{ID: 1, UUID: "user-uuid-1", Name: "Alice"}
+	f.userRoles["user-uuid-1"] = []string{otherRoleUUID}
+	f.roles = []*client.RoleDetails{{UUID: uuid.MustParse(basicRoleUUID), Name: "Basic", Type: "STANDARD"}}
+}
+
+func TestRoleGrant(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "assigns the role and keeps the other roles",
+			wantCalls: []string{"UpdateUserRoles"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, []string{otherRoleUUID, testRoleUUID}, f.userRoles["user-uuid-1"])
+			},
+		},
+		{
+			name:      "assigns the first role",
+			setup:     func(f *fakeTenable) { delete(f.userRoles, "user-uuid-1") },
+			wantCalls: []string{"UpdateUserRoles"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, []string{testRoleUUID}, f.userRoles["user-uuid-1"])
+			},
+		},
+		{
+			name:           "role is already assigned",
+			setup:          func(f *fakeTenable) { f.userRoles["user-uuid-1"] = []string{testRoleUUID} },
+			wantAnnotation: &v2.GrantAlreadyExists{},
+		},
+		{
+			name:    "user was deleted",
+			setup:   func(f *fakeTenable) { delete(f.users, "1") },
+			wantErr: client.ErrNotFound,
+		},
+		{
+			name:    "reading roles fails",
+			setup:   func(f *fakeTenable) { f.failures["GetUserRoles"] = errAPI },
+			wantErr: errAPI,
+		},
+		{
+			name:      "updating roles fails",
+			setup:     func(f *fakeTenable) { f.failures["UpdateUserRoles"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"UpdateUserRoles"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedRoles, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		role := newTestResource(roleResourceType, testRoleUUID)
+		return newRoleBuilder(c.client, c).Grant(ctx, newTestResource(userResourceType, "1"), newTestEntitlement(role, rolePermissionName))
+	})
+}
+
+func TestRoleRevoke(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "removes only the revoked role",
+			setup:     func(f *fakeTenable) { f.userRoles["user-uuid-1"] = []string{otherRoleUUID, testRoleUUID} },
+			wantCalls: []string{"UpdateUserRoles"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, []string{otherRoleUUID}, f.userRoles["user-uuid-1"])
+			},
+		},
+		{
+			name:      "falls back to the basic role",
+			setup:     func(f *fakeTenable) { f.userRoles["user-uuid-1"] = []string{testRoleUUID} },
+			wantCalls: []string{"UpdateUserRoles"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, []string{basicRoleUUID}, f.userRoles["user-uuid-1"])
+			},
+		},
+		{
+			name: "basic role is the only role",
+			setup: func(f *fakeTenable) {
+				f.roles[0].UUID = uuid.MustParse(testRoleUUID)
+				f.userRoles["user-uuid-1"] = []string{testRoleUUID}
+			},
+			wantErr: errBasicRoleOnly,
+		},
+		{
+			name: "updating roles fails",
+			setup: func(f *fakeTenable) {
+				f.userRoles["user-uuid-1"] = []string{otherRoleUUID, testRoleUUID}
+				f.failures["UpdateUserRoles"] = errAPI
+			},
+			wantErr:   errAPI,
+			wantCalls: []string{"UpdateUserRoles"},
+		},
+		{
+			name:           "role is not assigned",
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:           "user was deleted",
+			setup:          func(f *fakeTenable) { delete(f.users, "1") },
+			wantAnnotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:    "reading the user fails",
+			setup:   func(f *fakeTenable) { f.failures["GetUserDetails"] = errAPI },
+			wantErr: errAPI,
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedRoles, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		role := newTestResource(roleResourceType, testRoleUUID)
+		return newRoleBuilder(c.client, c).Revoke(ctx, &v2.Grant{
+			Principal:   newTestResource(userResourceType, "1"),
+			Entitlement: newTestEntitlement(role, rolePermissionName),
+		})
+	})
+}
+
+func seedCustomRoles(f *fakeTenable) {
+	seedRoles(f)
+	f.roles = append(f.roles, &client.RoleDetails{
+		UUID:        uuid.MustParse(testRoleUUID),
+		Name:        "Scanner",
+		Description: "Runs scans",
+		Permissions: []string{"SCAN.VIEW"},
+		Type:        customRoleType,
+	})
+}
+
+func TestRoleCreate(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []struct {
+		provisioningTestCase
+		roleName string
+	}{
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "creates the role",
+				wantCalls: []string{"CreateRole"},
+				check: func(t *testing.T, f *fakeTenable) {
+					require.Len(t, f.roles, 3)
+					require.Equal(t, "Auditor", f.roles[2].Name)
+					require.Equal(t, "Reviews findings", f.roles[2].Description)
+					require.Equal(t, []string{"VM.VIEW", "FINDINGS.VIEW"}, f.roles[2].Permissions)
+				},
+			},
+			roleName: "Auditor",
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "updates the existing custom role",
+				wantCalls: []string{"UpdateRole"},
+				check: func(t *testing.T, f *fakeTenable) {
+					require.Len(t, f.roles, 2)
+					require.Equal(t, "Reviews findings", f.roles[1].Description)
+					require.Equal(t, []string{"VM.VIEW", "FINDINGS.VIEW"}, f.roles[1].Permissions)
+				},
+			},
+			roleName: "scanner",
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name: "existing role already matches",
+				setup: func(f *fakeTenable) {
+					f.roles[1].Description = "Reviews findings"
+					f.roles[1].Permissions = []string{"VM.VIEW", "FINDINGS.VIEW"}
+				},
+			},
+			roleName: "Scanner",
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:    "name of a built-in role",
+				wantErr: errBuiltInRole,
+			},
+			roleName: "Basic",
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "creating the role fails",
+				setup:     func(f *fakeTenable) { f.failures["CreateRole"] = errAPI },
+				wantErr:   errAPI,
+				wantCalls: []string{"CreateRole"},
+			},
+			roleName: "Auditor",
+		},
+	}
+
+	for _, tc := range testCases {
+		runProvisioningTestCases(t, []provisioningTestCase{tc.provisioningTestCase}, seedCustomRoles, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+			profile, err := structpb.NewStruct(map[string]any{"permissions": []any{"VM.VIEW", "FINDINGS.VIEW"}})
+			require.NoError(t, err)
+			resource, annos, err := newRoleBuilder(c.client, c).Create(ctx, &v2.Resource{
+				Id:          &v2.ResourceId{ResourceType: roleResourceType.Id},
+				DisplayName: tc.roleName,
+				Description: "Reviews findings",
+				Annotations: annotations.New(&v2.RoleTrait{Profile: profile}),
+			})
+			if err == nil {
+				require.Equal(t, roleResourceType.Id, resource.Id.ResourceType)
+				require.True(t, strings.EqualFold(tc.roleName, resource.DisplayName))
+			}
+			return annos, err
+		})
+	}
+}
+
+func TestRolePermissionStrings(t *testing.T) {
+	profile, err := structpb.NewStruct(map[string]any{"permissions": "VM.VIEW, FINDINGS.VIEW"})
+	require.NoError(t, err)
+	permissions, err := rolePermissionStrings(&v2.Resource{Annotations: annotations.New(&v2.RoleTrait{Profile: profile})})
+	require.NoError(t, err)
+	require.Equal(t, []string{"VM.VIEW", "FINDINGS.VIEW"}, permissions)
+
+	permissions, err = rolePermissionStrings(&v2.Resource{})
+	require.NoError(t, err)
+	require.Empty(t, permissions)
+}
+
+func TestRoleDelete(t *testing.T) {
+	errAPI := errors.New("api down")
+	assign := func(f *fakeTenable) {
+		f.users["1"].RbacRoles = []client.Role{{UUID: uuid.MustParse(testRoleUUID), Name: "Scanner"}}
+		f.userRoles["user-uuid-1"] = []string{testRoleUUID}
+	}
+	testCases := []struct {
+		provisioningTestCase
+		roleId string
+	}{
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "deletes the custom role",
+				wantCalls: []string{"DeleteRole"},
+				check: func(t *testing.T, f *fakeTenable) {
+					require.Len(t, f.roles, 1)
+				},
+			},
+			roleId: testRoleUUID,
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name: "role was already deleted",
+			},
+			roleId: otherRoleUUID,
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:    "built-in role",
+				wantErr: errBuiltInRole,
+			},
+			roleId: basicRoleUUID,
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:    "role is assigned",
+				setup:   assign,
+				wantErr: errRoleAssigned,
+			},
+			roleId: testRoleUUID,
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:          "forced deletion of an assigned role",
+				connectorOpts: []Option{WithForceRoleDeletion(true)},
+				setup:         assign,
+				wantCalls:     []string{"UpdateUserRoles", "DeleteRole"},
+				check: func(t *testing.T, f *fakeTenable) {
+					require.Equal(t, []string{basicRoleUUID}, f.userRoles["user-uuid-1"])
+					require.Len(t, f.roles, 1)
+				},
+			},
+			roleId: testRoleUUID,
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "deleting the role fails",
+				setup:     func(f *fakeTenable) { f.failures["DeleteRole"] = errAPI },
+				wantErr:   errAPI,
+				wantCalls: []string{"DeleteRole"},
+			},
+			roleId: testRoleUUID,
+		},
+	}
+
+	for _, tc := range testCases {
+		runProvisioningTestCases(t, []provisioningTestCase{tc.provisioningTestCase}, seedCustomRoles, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+			return newRoleBuilder(c.client, c).Delete(ctx, &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: tc.roleId})
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package connector_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/connectorclient"
+	"github.com/conductorone/baton-sdk/pkg/dotc1z"
+	sdkSync "github.com/conductorone/baton-sdk/pkg/sync"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/conductorone/baton-tenable-vm/pkg/client/faketenable"
+	"github.com/conductorone/baton-tenable-vm/pkg/connector"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	aliceUUID      = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000001")
+	bobUUID        = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000002")
+	carolUUID      = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000003")
+	opsGroupUUID   = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000010")
+	adminRoleUUID  = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000020")
+	basicRoleUUID  = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000021")
+	scanPermission = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000030")
+	envTagUUID     = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000040")
+	prodTagUUID    = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000041")
+	devTagUUID     = uuid.MustParse("0b6a3c1e-7a0c-4c2b-9f55-000000000042")
+)
+
+// fillerPermissions pushes the permission list past a single page.
+const fillerPermissions = client.DefaultPageSize + 20
+
+func seedState() faketenable.State {
+	state := faketenable.State{
+		SessionUserID: 1,
+		Users: []client.User{
+			{ID: 1, UUID: aliceUUID.String(), Username: "alice@example.com", Email: "alice@example.com", Name: "Alice", Enabled: true, Permissions: 64},
+			{ID: 2, UUID: bobUUID.String(), Username: "bob@example.com", Email: "bob@example.com", Name: "Bob", Enabled: true, Permissions: 16},
+			{ID: 3, UUID: carolUUID.String(), Username: "carol@example.com", Email: "carol@example.com", Name: "Carol", Permissions: 16},
+		},
+		Groups: []client.Group{
+			{ID: 10, UUID: opsGroupUUID.String(), Name: "Ops"},
+		},
+		GroupMembers: map[int][]int{10: {1, 2}},
+		Roles: []client.RoleDetails{
+			{UUID: adminRoleUUID, Name: "Administrator", Type: "STANDARD", Permissions: []string{"SCAN.VIEW", "USER.MANAGE"}},
+			{UUID: basicRoleUUID, Name: "Basic", Type: "STANDARD", Permissions: []string{"SCAN.VIEW"}},
+		},
+		UserRoles: map[string][]string{
+			aliceUUID.String(): {adminRoleUUID.String()},
+			bobUUID.String():   {basicRoleUUID.String()},
+			carolUUID.String(): {basicRoleUUID.String()},
+		},
+		Authorizations: map[int]client.UserAuthorizations{
+			1: {APIPermitted: true, PasswordPermitted: true},
+			2: {PasswordPermitted: true},
+			3: {SAMLPermitted: true},
+		},
+		TagCategories: []client.TagCategory{
+			{UUID: envTagUUID.String(), Name: "Env"},
+		},
+		TagValues: []client.TagValue{
+			{UUID: prodTagUUID.String(), CategoryUUID: envTagUUID.String(), CategoryName: "Env", Value: "Prod"},
+			{UUID: devTagUUID.String(), CategoryUUID: envTagUUID.String(), CategoryName: "Env", Value: "Dev"},
+		},
+		Permissions: []client.Permission{
+			{
+				UUID:    scanPermission,
+				Name:    "Scan production",
+				Actions: []string{"CanView", "CanScan"},
+				Objects: []client.TenableObject{{Type: "Tag", Name: "Env:Prod"}},
+				Subjects: []client.TenableObject{
+					{Type: "User", UUID: carolUUID, Name: "carol@example.com"},
+					{Type: "UserGroup", UUID: opsGroupUUID, Name: "Ops"},
+				},
+			},
+		},
+	}
+	for i := range fillerPermissions {
+		state.Permissions = append(state.Permissions, client.Permission{
+			UUID:    uuid.New(),
+			Name:    "Filler " + strconv.Itoa(i),
+			Actions: []string{"CanView"},
+		})
+	}
+	return state
+}
+
+// newConnectorClient serves the connector over a loopback gRPC server, the way the connector runner does.
+func newConnectorClient(ctx context.Context, t *testing.T, c *connector.Connector) *grpc.ClientConn {
+	srv, err := connectorbuilder.NewConnector(ctx, c)
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	v2.RegisterConnectorServiceServer(s, srv)
+	v2.RegisterResourceTypesServiceServer(s, srv)
+	v2.RegisterResourcesServiceServer(s, srv)
+	v2.RegisterResourceGetterServiceServer(s, srv)
+	v2.RegisterEntitlementsServiceServer(s, srv)
+	v2.RegisterGrantsServiceServer(s, srv)
+	v2.RegisterAssetServiceServer(s, srv)
+	v2.RegisterEventServiceServer(s, srv)
+	v2.RegisterGrantManagerServiceServer(s, srv)
+	v2.RegisterAccountManagerServiceServer(s, srv)
+	v2.RegisterResourceManagerServiceServer(s, srv)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = s.Serve(listener) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func syncToC1Z(ctx context.Context, t *testing.T, server *faketenable.Server, secretKey string) (string, error) {
+	c, err := connector.New(ctx, server.URL, faketenable.AccessKey, secretKey,
+		connector.WithClientOptions(client.WithRetryPolicy(client.RetryPolicy{})))
+	require.NoError(t, err)
+	conn := newConnectorClient(ctx, t, c)
+
+	c1zPath := filepath.Join(t.TempDir(), "sync.c1z")
+	syncer, err := sdkSync.NewSyncer(ctx, connectorclient.NewConnectorClient(ctx, conn),
+		sdkSync.WithC1ZPath(c1zPath),
+		sdkSync.WithTmpDir(t.TempDir()),
+	)
+	require.NoError(t, err)
+
+	err = syncer.Sync(ctx)
+	require.NoError(t, syncer.Close(ctx))
+	return c1zPath, err
+}
+
+func listResources(ctx context.Context, t *testing.T, store *dotc1z.C1File, resourceType string) map[string]string {
+	resources := make(map[string]string)
+	pageToken := ""
+	for {
+		res, err := store.ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{
+			ResourceTypeId: resourceType,
+			PageToken:      pageToken,
+		})
+		require.NoError(t, err)
+		for _, resource := range res.List {
+			resources[resource.Id.Resource] = resource.DisplayName
+		}
+		if res.NextPageToken == "" {
+			return resources
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+func listEntitlements(ctx context.Context, t *testing.T, store *dotc1z.C1File) []string {
+	var entitlements []string
+	pageToken := ""
+	for {
+		res, err := store.ListEntitlements(ctx, &v2.EntitlementsServiceListEntitlementsRequest{PageToken: pageToken})
+		require.NoError(t, err)
+		for _, ent := range res.List {
+			entitlements = append(entitlements, ent.Id)
+		}
+		if res.NextPageToken == "" {
+			return entitlements
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+// listGrants returns the grants as "entitlement id -> principal type:principal id".
+func listGrants(ctx context.Context, t *testing.T, store *dotc1z.C1File) []string {
+	var grants []string
+	pageToken := ""
+	for {
+		res, err := store.ListGrants(ctx, &v2.GrantsServiceListGrantsRequest{PageToken: pageToken})
+		require.NoError(t, err)
+		for _, g := range res.List {
+			grants = append(grants, fmt.Sprintf("%s -> %s:%s", g.Entitlement.Id, g.Principal.Id.ResourceType, g.Principal.Id.Resource))
+		}
+		if res.NextPageToken == "" {
+			return grants
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+func TestSync(t *testing.T) {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+	ctx := context.Background()
+	server := faketenable.New(seedState())
+	defer server.Close()
+
+	c1zPath, err := syncToC1Z(ctx, t, server, faketenable.SecretKey)
+	require.NoError(t, err)
+
+	store, err := dotc1z.NewC1ZFile(ctx, c1zPath, dotc1z.WithTmpDir(t.TempDir()))
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.Equal(t, map[string]string{"1": "Alice", "2": "Bob", "3": "Carol"}, listResources(ctx, t, store, "user"))
+	require.Equal(t, map[string]string{"10": "Ops"}, listResources(ctx, t, store, "group"))
+	require.Equal(t, map[string]string{
+		adminRoleUUID.String(): "Administrator",
+		basicRoleUUID.String(): "Basic",
+	}, listResources(ctx, t, store, "role"))
+
+	permissions := listResources(ctx, t, store, "permission")
+	require.Len(t, permissions, fillerPermissions+1)
+	require.Equal(t, "Scan production", permissions[scanPermission.String()])
+
+	require.Equal(t, map[string]string{
+		"authorizations": "Login Methods",
+	}, listResources(ctx, t, store, "login_method"))
+
+	require.Equal(t, map[string]string{
+		"SCAN.VIEW":   "SCAN.VIEW",
+		"USER.MANAGE": "USER.MANAGE",
+	}, listResources(ctx, t, store, "capability"))
+
+	require.Equal(t, map[string]string{envTagUUID.String(): "Env"}, listResources(ctx, t, store, "tag_category"))
+	require.Equal(t, map[string]string{
+		prodTagUUID.String(): "Env:Prod",
+		devTagUUID.String():  "Env:Dev",
+	}, listResources(ctx, t, store, "tag_value"))
+
+	entitlements := listEntitlements(ctx, t, store)
+	// Every permission also has an entitlement per action: CanView on the fillers, CanView and CanScan on the other.
+	// Every tag value has one per tag action: CanView, CanScan, CanEdit and CanUse.
+	require.Len(t, entitlements, 1+2+2*fillerPermissions+3+3+5+2+2*4)
+	require.Contains(t, entitlements, "user_type:64:assigned")
+	require.Contains(t, entitlements, "group:10:member")
+	require.Contains(t, entitlements, "role:"+adminRoleUUID.String()+":assigned")
+	require.Contains(t, entitlements, "permission:"+scanPermission.String()+":assigned")
+	require.Contains(t, entitlements, "permission:"+scanPermission.String()+":CanScan")
+
+	scanAssigned := "permission:" + scanPermission.String() + ":assigned"
+	scanView := "permission:" + scanPermission.String() + ":CanView"
+	scanScan := "permission:" + scanPermission.String() + ":CanScan"
+	prodView := "tag_value:" + prodTagUUID.String() + ":CanView"
+	prodScan := "tag_value:" + prodTagUUID.String() + ":CanScan"
+	require.ElementsMatch(t, []string{
+		"group:10:member -> user:1",
+		"group:10:member -> user:2",
+		"role:" + adminRoleUUID.String() + ":assigned -> user:1",
+		"role:" + basicRoleUUID.String() + ":assigned -> user:2",
+		"role:" + basicRoleUUID.String() + ":assigned -> user:3",
+		scanAssigned + " -> user:3",
+		scanAssigned + " -> group:10",
+		// Expanded from the Ops group subject.
+		scanAssigned + " -> user:1",
+		scanAssigned + " -> user:2",
+		scanView + " -> user:3",
+		scanView + " -> group:10",
+		scanView + " -> user:1",
+		scanView + " -> user:2",
+		scanScan + " -> user:3",
+		scanScan + " -> group:10",
+		scanScan + " -> user:1",
+		scanScan + " -> user:2",
+		"login_method:authorizations:api -> user:1",
+		"login_method:authorizations:password -> user:1",
+		"login_method:authorizations:password -> user:2",
+		"login_method:authorizations:saml -> user:3",
+		"user_type:64:assigned -> user:1",
+		"user_type:16:assigned -> user:2",
+		"user_type:16:assigned -> user:3",
+		"capability:SCAN.VIEW:granted -> role:" + adminRoleUUID.String(),
+		"capability:SCAN.VIEW:granted -> role:" + basicRoleUUID.String(),
+		"capability:USER.MANAGE:granted -> role:" + adminRoleUUID.String(),
+		// Expanded from the roles.
+		"capability:SCAN.VIEW:granted -> user:1",
+		"capability:SCAN.VIEW:granted -> user:2",
+		"capability:SCAN.VIEW:granted -> user:3",
+		"capability:USER.MANAGE:granted -> user:1",
+		// The scan permission is scoped to the Env:Prod tag.
+		prodView + " -> user:3",
+		prodView + " -> group:10",
+		prodView + " -> user:1",
+		prodView + " -> user:2",
+		prodScan + " -> user:3",
+		prodScan + " -> group:10",
+		prodScan + " -> user:1",
+		prodScan + " -> user:2",
+	}, listGrants(ctx, t, store))
+}
+
+func TestSyncInvalidCredentials(t *testing.T) {
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+	ctx := context.Background()
+	server := faketenable.New(seedState())
+	defer server.Close()
+
+	_, err := syncToC1Z(ctx, t, server, "wrong-secret")
+	require.Error(t, err)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,242 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const objectTypeTag = "Tag"
+
+// tagActions are the actions a permission can grant on the assets carrying a tag. Every tag value has an
+// entitlement for each of them, plus any other action found on a permission scoped to it.
+var tagActions = []string{"CanView", "CanScan", "CanEdit", "CanUse"}
+
+type tagCategoryBuilder struct {
+	client client.TenableAPI
+}
+
+func (o *tagCategoryBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return tagCategoryResourceType
+}
+
+// List returns the tag categories, their values are synced as child resources.
+func (o *tagCategoryBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	pageOpts, err := client.ParsePageToken(pToken.Token, pToken.Size)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	categories, nextToken, annos, err := o.client.ListTagCategories(ctx, pageOpts)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("failed to list tag categories: %w", err)
+	}
+
+	var resources []*v2.Resource
+	for _, category := range categories {
+		categoryResource, err := rs.NewResource(
+			category.Name,
+			tagCategoryResourceType,
+			category.UUID,
+			rs.WithDescription(category.Description),
+			rs.WithParentResourceID(parentResourceID),
+			rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: tagValueResourceType.Id}),
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, categoryResource)
+	}
+	return resources, nextToken, annos, nil
+}
+
+// Entitlements always returns an empty slice, access is held on the tag values.
+func (o *tagCategoryBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice, access is held on the tag values.
+func (o *tagCategoryBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newTagCategoryBuilder(c client.TenableAPI) *tagCategoryBuilder {
+	return &tagCategoryBuilder{
+		client: c,
+	}
+}
+
+// tagValueBuilder syncs the values of each tag category. Their grants show which users and groups hold which
+// actions on the tagged assets, derived from the access control permissions scoped to the tag.
+type tagValueBuilder struct {
+	client    client.TenableAPI
+	connector *Connector
+}
+
+func (o *tagValueBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return tagValueResourceType
+}
+
+// List returns the values of the parent tag category.
+func (o *tagValueBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != tagCategoryResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	pageOpts, err := client.ParsePageToken(pToken.Token, pToken.Size)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	values, nextToken, annos, err := o.client.ListTagValues(ctx, parentResourceID.Resource, pageOpts)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("failed to list values of tag category %s: %w", parentResourceID.Resource, err)
+	}
+
+	var resources []*v2.Resource
+	for _, value := range values {
+		valueResource, err := rs.NewResource(
+			tagName(value),
+			tagValueResourceType,
+			value.UUID,
+			rs.WithDescription(value.Description),
+			rs.WithParentResourceID(parentResourceID),
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, valueResource)
+	}
+	return resources, nextToken, annos, nil
+}
+
+// Entitlements returns one entitlement per action that can be held on the tagged assets.
+func (o *tagValueBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	permissions, annos, err := o.connector.cachePermissions(ctx)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("failed to cache permissions: %w", err)
+	}
+
+	actions := slices.Clone(tagActions)
+	for _, permission := range permissionsScopedTo(permissions, resource) {
+		for _, action := range permission.Actions {
+			if !slices.Contains(actions, action) {
+				actions = append(actions, action)
+			}
+		}
+	}
+
+	// Access changes through the permissions, these are not grantable.
+	var entitlements []*v2.Entitlement
+	for _, action := range actions {
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(
+			resource,
+			action,
+			entitlement.WithDescription(fmt.Sprintf("%s on the assets tagged %s", action, resource.DisplayName)),
+			entitlement.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, action)),
+		))
+	}
+	return entitlements, "", nil, nil
+}
+
+// Grants returns a grant for each action every subject of a permission scoped to the tag holds. The grants are
+// immutable, access changes through the permissions, and those of groups expand to the group members.
+func (o *tagValueBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	permissions, annos, err := o.connector.cachePermissions(ctx)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("failed to cache permissions: %w", err)
+	}
+	users, annos, err := o.connector.cacheUsers(ctx)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("failed to cache users: %w", err)
+	}
+	groups, annos, err := o.connector.cacheGroups(ctx)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("failed to cache groups: %w", err)
+	}
+
+	var (
+		grants []*v2.Grant
+		seen   = make(map[string]bool)
+	)
+	for _, permission := range permissionsScopedTo(permissions, resource) {
+		for _, subject := range permission.Subjects {
+			var (
+				principalID *v2.ResourceId
+				err         error
+			)
+			grantOpts := []grant.GrantOption{grant.WithAnnotation(&v2.GrantImmutable{})}
+			switch subject.Type {
+			case subjectTypeUser:
+				principalID, err = getUserResourceId(subject.UUID.String(), users)
+			case subjectTypeGroup:
+				principalID, err = getGroupResourceId(subject.UUID.String(), groups)
+				if err == nil {
+					grantOpts = append(grantOpts, grant.WithAnnotation(&v2.GrantExpandable{
+						EntitlementIds: []string{fmt.Sprintf("group:%s:member", principalID.Resource)},
+					}))
+				}
+			default:
+				continue
+			}
+			if err != nil {
+				l.Debug("Skipping unknown permission subject", zap.String("permission_uuid", permission.UUID.String()), zap.Error(err))
+				continue
+			}
+
+			for _, action := range permission.Actions {
+				key := action + "/" + principalID.ResourceType + "/" + principalID.Resource
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				grants = append(grants, grant.NewGrant(resource, action, principalID, grantOpts...))
+			}
+		}
+	}
+	return grants, "", nil, nil
+}
+
+// permissionsScopedTo returns the permissions with the tag value among their objects. Objects are matched on
+// uuid, or on the category:value name when the permission does not carry the uuid.
+func permissionsScopedTo(permissions []*client.Permission, resource *v2.Resource) []*client.Permission {
+	var scoped []*client.Permission
+	for _, permission := range permissions {
+		if slices.ContainsFunc(permission.Objects, func(obj client.TenableObject) bool {
+			if obj.Type != objectTypeTag {
+				return false
+			}
+			if obj.UUID.String() == resource.Id.Resource {
+				return true
+			}
+			// Tag names are category:value, but category,value when written back to the API.
+			return strings.EqualFold(strings.Replace(obj.Name, ",", ":", 1), resource.DisplayName)
+		}) {
+			scoped = append(scoped, permission)
+		}
+	}
+	return scoped
+}
+
+// tagName is the category:value name Tenable shows for a tag.
+func tagName(value client.TagValue) string {
+	return value.CategoryName + ":" + value.Value
+}
+
+func newTagValueBuilder(c client.TenableAPI, conn *Connector) *tagValueBuilder {
+	return &tagValueBuilder{
+		client:    c,
+		connector: conn,
+	}
+}
@@ -0,0 +1,135 @@
+package connector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTagCategoryUUID = "7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0300"
+	testProdTagUUID     = "7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0301"
+	testDevTagUUID      = "7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0302"
+)
+
+func seedTags(f *fakeTenable) {
+	seedGroupPermissions(f)
+	withUserSubject(f)
+	withGroupSubject(f)
+	f.tagCategories = []client.TagCategory{{UUID: testTagCategoryUUID, Name: "Env"}}
+	f.tagValues = []client.TagValue{
+		{UUID: testProdTagUUID, CategoryUUID: testTagCategoryUUID, CategoryName: "Env", Value: "Prod"},
+		{UUID: testDevTagUUID, CategoryUUID: testTagCategoryUUID, CategoryName: "Env", Value: "Dev"},
+	}
+
+	// Scoped to the tag by uuid, with the name the API writes back, and a subject that no longer exists.
+	exportPermission := uuid.MustParse("7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0002")
+	f.permissions[exportPermission.String()] = &client.Permission{
+		UUID:    exportPermission,
+		Name:    "Export production",
+		Actions: []string{"CanView", "CanExport"},
+		Objects: []client.TenableObject{{Type: objectTypeTag, UUID: uuid.MustParse(testProdTagUUID), Name: "Env,Prod"}},
+		Subjects: []client.TenableObject{
+			{Type: subjectTypeUser, UUID: testUserUUID, Name: "Alice"},
+			{Type: subjectTypeUser, UUID: uuid.MustParse("7d0e1f52-3a43-4d8e-8b3a-2b7d7f0a0199"), Name: "Mallory"},
+		},
+	}
+}
+
+func TestTagList(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedTags(f)
+
+	categories, _, _, err := newTagCategoryBuilder(f).List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	require.Equal(t, "Env", categories[0].DisplayName)
+	annos := annotations.Annotations(categories[0].Annotations)
+	require.True(t, annos.Contains(&v2.ChildResourceType{}))
+
+	b := newTagValueBuilder(f, &Connector{client: f})
+	values, _, _, err := b.List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, values)
+
+	values, _, _, err = b.List(ctx, categories[0].Id, &pagination.Token{})
+	require.NoError(t, err)
+	var names []string
+	for _, value := range values {
+		require.Equal(t, categories[0].Id, value.ParentResourceId)
+		names = append(names, value.DisplayName)
+	}
+	require.Equal(t, []string{"Env:Prod", "Env:Dev"}, names)
+}
+
+func TestTagValueGrants(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedTags(f)
+	b := newTagValueBuilder(f, &Connector{client: f})
+
+	testCases := []struct {
+		name             string
+		valueUUID        string
+		displayName      string
+		wantEntitlements []string
+		wantGrants       []string
+	}{
+		{
+			name:             "permissions scoped by name and by uuid",
+			valueUUID:        testProdTagUUID,
+			displayName:      "Env:Prod",
+			wantEntitlements: []string{"CanView", "CanScan", "CanEdit", "CanUse", "CanExport"},
+			wantGrants: []string{
+				"CanView -> user:1",
+				"CanView -> group:10",
+				"CanScan -> user:1",
+				"CanScan -> group:10",
+				"CanExport -> user:1",
+			},
+		},
+		{
+			name:             "no permission is scoped to the tag",
+			valueUUID:        testDevTagUUID,
+			displayName:      "Env:Dev",
+			wantEntitlements: tagActions,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value := newTestResource(tagValueResourceType, tc.valueUUID)
+			value.DisplayName = tc.displayName
+
+			entitlements, _, _, err := b.Entitlements(ctx, value, nil)
+			require.NoError(t, err)
+			var slugs []string
+			for _, e := range entitlements {
+				slugs = append(slugs, e.Slug)
+				require.Empty(t, e.GrantableTo, e.Slug)
+			}
+			require.Equal(t, tc.wantEntitlements, slugs)
+
+			grants, _, _, err := b.Grants(ctx, value, &pagination.Token{})
+			require.NoError(t, err)
+			var got []string
+			for _, g := range grants {
+				slug := strings.TrimPrefix(g.Entitlement.Id, tagValueResourceType.Id+":"+tc.valueUUID+":")
+				got = append(got, slug+" -> "+g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource)
+
+				annos := annotations.Annotations(g.Annotations)
+				require.True(t, annos.Contains(&v2.GrantImmutable{}))
+				require.Equal(t, g.Principal.Id.ResourceType == groupResourceType.Id, annos.Contains(&v2.GrantExpandable{}))
+			}
+			require.ElementsMatch(t, tc.wantGrants, got)
+		})
+	}
+}
@@ -2,7 +2,10 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,10 +16,14 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
+var errPasswordLoginNotPermitted = errors.New("baton-tenable-vm: the user is not permitted to log in with a password, grant the password login method first")
+
 type userBuilder struct {
-	client    *client.TenableVMClient
+	client    client.TenableAPI
 	connector *Connector
 }
 
@@ -27,13 +34,11 @@ func (o *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (o *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	annos, err := o.connector.cacheUsers(ctx)
+	users, annos, err := o.connector.cacheUsers(ctx)
 	if err != nil {
 		return nil, "", annos, err
 	}
 
-	users := o.connector.cachedUsers
-
 	// Create a slice of resources to hold the user resources
 	var resources []*v2.Resource
 	for _, user := range users {
@@ -118,14 +123,30 @@ func getFirstNameAndLastName(name string) (string, string) {
 func (b *userBuilder) CreateAccountCapabilityDetails(
 	_ context.Context,
 ) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	annos, err := b.passwordPolicyAnnotations()
+	if err != nil {
+		return nil, nil, err
+	}
 	return &v2.CredentialDetailsAccountProvisioning{
 		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
 			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_SSO,
 		},
 		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
-	}, nil, nil
+	}, annos, nil
 }
 
+// passwordPolicyAnnotations describes the password policy, which the capability details have no field for.
+func (o *userBuilder) passwordPolicyAnnotations() (annotations.Annotations, error) {
+	policy, err := o.connector.getPasswordPolicy().describe()
+	if err != nil {
+		return nil, err
+	}
+	return annotations.New(policy), nil
+}
+
+// CreateAccount creates the user and then applies the role, groups and login methods requested in the account
+// profile. The user is deleted again if any of those steps fails, so a failed request leaves nothing behind.
 func (o *userBuilder) CreateAccount(
 	ctx context.Context,
 	accountInfo *v2.AccountInfo,
@@ -136,33 +157,64 @@ func (o *userBuilder) CreateAccount(
 	annotations.Annotations,
 	error,
 ) {
-	profile := accountInfo.GetProfile().AsMap()
+	l := ctxzap.Extract(ctx)
 
-	email, ok := profile["email"].(string)
-	if !ok {
-		return nil, nil, nil, fmt.Errorf("missing or invalid 'email' in profile")
+	account, err := o.parseAccountRequest(ctx, accountInfo.GetProfile().AsMap(), credentialOptions)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	name, ok := profile["name"].(string)
-	if !ok {
-		return nil, nil, nil, fmt.Errorf("missing or invalid 'name' in profile")
+
+	existingUser, err := o.findExistingUser(ctx, account)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if existingUser != nil {
+		return existingAccountResponse(ctx, existingUser)
 	}
 
-	generatedPassword, err := generateCredentials(credentialOptions)
+	// Tenable requires a password even for SAML-only users, who get a random one that is never handed out.
+	passwordOptions := credentialOptions
+	if account.samlOnly {
+		passwordOptions = &v2.CredentialOptions{
+			Options: &v2.CredentialOptions_RandomPassword_{
+				RandomPassword: &v2.CredentialOptions_RandomPassword{},
+			},
+		}
+	}
+	generatedPassword, err := generateCredentials(passwordOptions, o.connector.getPasswordPolicy())
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
 	userToCreate := client.NewUser{
-		Username:    email,
+		Username:    account.username,
 		Password:    generatedPassword,
-		Permissions: BasicUserRole,
-		Email:       email,
-		Name:        name,
+		Permissions: account.permissions,
+		Email:       account.email,
+		Name:        account.name,
 	}
 	createdUser, err := o.client.CreateUser(ctx, userToCreate)
 	if err != nil {
+		if errors.Is(err, client.ErrConflict) {
+			// Created by a concurrent request since the users were cached.
+			o.connector.invalidateUsersCache()
+			existingUser, lookupErr := o.findExistingUser(ctx, account)
+			if lookupErr == nil && existingUser != nil {
+				return existingAccountResponse(ctx, existingUser)
+			}
+		}
 		return nil, nil, nil, fmt.Errorf("failed to create user: %w", err)
 	}
+	o.connector.invalidateUsersCache()
+
+	if err := o.applyAccountSettings(ctx, createdUser, account); err != nil {
+		userId := strconv.Itoa(createdUser.ID)
+		l.Debug("Rolling back the created user", zap.String("user_id", userId), zap.Error(err))
+		if rollbackErr := o.client.DeleteUser(ctx, userId); rollbackErr != nil {
+			err = errors.Join(err, fmt.Errorf("baton-tenable-vm: failed to roll back user %s: %w", userId, rollbackErr))
+		}
+		return nil, nil, nil, err
+	}
 
 	userResource, err := parseIntoUserResource(ctx, createdUser, nil)
 
@@ -170,7 +222,12 @@ func (o *userBuilder) CreateAccount(
 		return nil, nil, nil, fmt.Errorf("failed to build resource: %w", err)
 	}
 	caResponse := &v2.CreateAccountResponse_SuccessResult{
-		Resource: userResource,
+		Resource:              userResource,
+		IsCreateAccountResult: true,
+	}
+
+	if account.samlOnly {
+		return caResponse, nil, nil, nil
 	}
 
 	passResult := &v2.PlaintextData{
@@ -181,7 +238,307 @@ func (o *userBuilder) CreateAccount(
 	return caResponse, []*v2.PlaintextData{passResult}, nil, nil
 }
 
-func newUserBuilder(c *client.TenableVMClient, con *Connector) *userBuilder {
+// findExistingUser returns the user with the requested username or email, or nil if there is none.
+func (o *userBuilder) findExistingUser(ctx context.Context, account *accountRequest) (*client.User, error) {
+	// Neither the connector's nor the HTTP cache will do: a user deleted since must be created again.
+	users, _, err := o.client.GetUsers(client.WithoutCache(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range users {
+		user := &users[i]
+		if strings.EqualFold(user.Username, account.username) ||
+			(user.Email != "" && strings.EqualFold(user.Email, account.email)) {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// existingAccountResponse reports an account that was already there. Its password is unknown, so none is returned.
+func existingAccountResponse(ctx context.Context, user *client.User) (
+	connectorbuilder.CreateAccountResponse,
+	[]*v2.PlaintextData,
+	annotations.Annotations,
+	error,
+) {
+	ctxzap.Extract(ctx).Debug("User already exists, not creating it again",
+		zap.Int("user_id", user.ID),
+		zap.String("username", user.Username),
+	)
+
+	userResource, err := parseIntoUserResource(ctx, user, nil)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to build resource: %w", err)
+	}
+	return &v2.CreateAccountResponse_SuccessResult{
+		Resource:              userResource,
+		IsCreateAccountResult: false,
+	}, nil, nil, nil
+}
+
+// accountRequest is the account profile of CreateAccount, with the role and groups resolved to Tenable IDs.
+type accountRequest struct {
+	name        string
+	email       string
+	username    string
+	permissions int
+	roleUUID    string
+	groupIDs    []string
+	samlOnly    bool
+}
+
+// parseAccountRequest validates the account profile and resolves the role and groups it names. Nothing is
+// changed in Tenable, so a bad profile is rejected before the user is created.
+func (o *userBuilder) parseAccountRequest(
+	ctx context.Context,
+	profile map[string]interface{},
+	credentialOptions *v2.CredentialOptions,
+) (*accountRequest, error) {
+	email, ok := profile["email"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid 'email' in profile")
+	}
+	name, ok := profile["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid 'name' in profile")
+	}
+
+	account := &accountRequest{
+		name:        name,
+		email:       email,
+		username:    email,
+		permissions: BasicUserRole,
+	}
+	if username, ok := profile["username"].(string); ok && strings.TrimSpace(username) != "" {
+		account.username = strings.TrimSpace(username)
+	}
+	if samlOnly, ok := profile["saml_only"].(bool); ok {
+		account.samlOnly = samlOnly
+	}
+	if credentialOptions.GetSso() != nil {
+		account.samlOnly = true
+	}
+
+	value, hasPermissions := profile["permissions"]
+	if hasPermissions && value != nil {
+		permissions, err := parseProfilePermissions(value)
+		if err != nil {
+			return nil, err
+		}
+		account.permissions = permissions
+	}
+
+	if roleRef, ok := profile["role"].(string); ok && strings.TrimSpace(roleRef) != "" {
+		if hasPermissions && value != nil {
+			return nil, fmt.Errorf("set either 'permissions' or 'role' in profile, not both")
+		}
+		roleUUID, err := o.resolveRole(ctx, strings.TrimSpace(roleRef))
+		if err != nil {
+			return nil, err
+		}
+		account.roleUUID = roleUUID
+	}
+
+	if groupRefs, ok := profile["groups"].([]interface{}); ok && len(groupRefs) > 0 {
+		groupIDs, err := o.resolveGroups(ctx, groupRefs)
+		if err != nil {
+			return nil, err
+		}
+		account.groupIDs = groupIDs
+	}
+
+	return account, nil
+}
+
+// parseProfilePermissions accepts a legacy permission level as a number or as a string holding one.
+func parseProfilePermissions(value interface{}) (int, error) {
+	var resourceId string
+	switch v := value.(type) {
+	case float64:
+		resourceId = strconv.Itoa(int(v))
+	case string:
+		resourceId = strings.TrimSpace(v)
+	default:
+		return 0, fmt.Errorf("invalid 'permissions' in profile: %v", value)
+	}
+	permissions, err := parseUserType(resourceId)
+	if err != nil {
+		return 0, fmt.Errorf("invalid 'permissions' in profile: %w", err)
+	}
+	return permissions, nil
+}
+
+// resolveRole looks a role up by UUID or name.
+func (o *userBuilder) resolveRole(ctx context.Context, roleRef string) (string, error) {
+	roles, _, err := o.client.GetRoles(ctx)
+	if err != nil {
+		return "", fmt.Errorf("baton-tenable-vm: failed to list roles: %w", err)
+	}
+	for _, role := range roles {
+		if role.UUID.String() == roleRef || strings.EqualFold(role.Name, roleRef) {
+			return role.UUID.String(), nil
+		}
+	}
+	return "", fmt.Errorf("baton-tenable-vm: role %s not found", roleRef)
+}
+
+// resolveGroups looks groups up by ID, UUID or name and returns their IDs.
+func (o *userBuilder) resolveGroups(ctx context.Context, groupRefs []interface{}) ([]string, error) {
+	groups, _, err := o.client.GetGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("baton-tenable-vm: failed to list groups: %w", err)
+	}
+
+	groupIDs := make([]string, 0, len(groupRefs))
+	for _, value := range groupRefs {
+		groupRef, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid 'groups' in profile: %v", value)
+		}
+		groupRef = strings.TrimSpace(groupRef)
+		idx := slices.IndexFunc(groups, func(group client.Group) bool {
+			return strconv.Itoa(group.ID) == groupRef || group.UUID == groupRef || strings.EqualFold(group.Name, groupRef)
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("baton-tenable-vm: group %s not found", groupRef)
+		}
+		groupIDs = append(groupIDs, strconv.Itoa(groups[idx].ID))
+	}
+	return groupIDs, nil
+}
+
+// applyAccountSettings configures a freshly created user as the account request asks.
+func (o *userBuilder) applyAccountSettings(ctx context.Context, user *client.User, account *accountRequest) error {
+	userId := strconv.Itoa(user.ID)
+
+	if account.roleUUID != "" {
+		_, err := o.client.UpdateUserRoles(ctx, user.UUID, []string{account.roleUUID})
+		if err != nil {
+			return fmt.Errorf("baton-tenable-vm: failed to assign role %s to user %s: %w", account.roleUUID, userId, err)
+		}
+	}
+
+	for _, groupId := range account.groupIDs {
+		err := o.client.CreateUserGroupMembership(ctx, groupId, userId, true)
+		if err != nil {
+			return fmt.Errorf("baton-tenable-vm: failed to add user %s to group %s: %w", userId, groupId, err)
+		}
+	}
+
+	if account.samlOnly {
+		err := o.client.UpdateUserAuthorizations(ctx, userId, client.UserAuthorizations{SAMLPermitted: true})
+		if err != nil {
+			return fmt.Errorf("baton-tenable-vm: failed to restrict user %s to SAML logins: %w", userId, err)
+		}
+	}
+
+	return nil
+}
+
+// Credential rotation.
+func (o *userBuilder) RotateCapabilityDetails(_ context.Context) (*v2.CredentialDetailsCredentialRotation, annotations.Annotations, error) {
+	annos, err := o.passwordPolicyAnnotations()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &v2.CredentialDetailsCredentialRotation{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+	}, annos, nil
+}
+
+// Rotate sets a new random password for a local Tenable user and returns it. Users who cannot log in with a
+// password, e.g. SAML only users, are refused rather than given a password they cannot use.
+func (o *userBuilder) Rotate(
+	ctx context.Context,
+	resourceId *v2.ResourceId,
+	credentialOptions *v2.CredentialOptions,
+) ([]*v2.PlaintextData, annotations.Annotations, error) {
+	if resourceId.ResourceType != userResourceType.Id {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: cannot rotate credentials of resource type %s", resourceId.ResourceType)
+	}
+	userId := resourceId.Resource
+
+	generatedPassword, err := generateCredentials(credentialOptions, o.connector.getPasswordPolicy())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authorizations, err := o.client.GetUserAuthorizations(client.WithoutCache(ctx), userId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: failed to get authorizations of user %s: %w", userId, err)
+	}
+	if !authorizations.PasswordPermitted {
+		return nil, nil, fmt.Errorf("%w: user %s", errPasswordLoginNotPermitted, userId)
+	}
+
+	err = o.client.ChangeUserPassword(ctx, userId, generatedPassword)
+	if err != nil {
+		return nil, nil, fmt.Errorf("baton-tenable-vm: failed to rotate password for user %s: %w", userId, err)
+	}
+
+	passResult := &v2.PlaintextData{
+		Name:  "password",
+		Bytes: []byte(generatedPassword),
+	}
+
+	return []*v2.PlaintextData{passResult}, nil, nil
+}
+
+// Delete removes the user. Scans, policies and managed credentials the user owns are first handed over to the
+// transfer-ownership-to user, and the returned annotations list what was moved.
+func (o *userBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	userId := resourceId.Resource
+
+	user, err := o.client.GetUserDetails(ctx, userId)
+	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			l.Debug("User no longer exists, nothing to delete", zap.String("user_id", userId))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("baton-tenable-vm: failed to get user %s: %w", userId, err)
+	}
+
+	owned, err := o.connector.listOwnedObjects(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("baton-tenable-vm: failed to list objects owned by user %s: %w", userId, err)
+	}
+
+	var annos annotations.Annotations
+	if owned.count() > 0 {
+		if o.connector.ownershipTarget == "" {
+			return nil, fmt.Errorf("%w: user %s owns %d scans, %d policies and %d managed credentials",
+				errOwnershipTransferRequired, userId, len(owned.scans), len(owned.policies), len(owned.credentials))
+		}
+		target, err := o.connector.ownershipTransferTarget(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if target.ID == user.ID {
+			return nil, fmt.Errorf("%w: user %s", errOwnershipTargetDeleted, userId)
+		}
+		report, err := o.connector.transferOwnership(ctx, owned, user, target)
+		if err != nil {
+			return nil, fmt.Errorf("baton-tenable-vm: failed to transfer ownership from user %s: %w", userId, err)
+		}
+		annos.Update(report)
+	}
+
+	err = o.client.DeleteUser(ctx, userId)
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		return annos, fmt.Errorf("baton-tenable-vm: failed to delete user %s: %w", userId, err)
+	}
+	o.connector.invalidateUsersCache()
+
+	return annos, nil
+}
+
+func newUserBuilder(c client.TenableAPI, con *Connector) *userBuilder {
 	return &userBuilder{
 		client:    c,
 		connector: con,
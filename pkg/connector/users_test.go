@@ -0,0 +1,512 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func seedUsers(f *fakeTenable) {
+	f.users["1"] = &client.User{ID: 1, UUID: "user-uuid-1", Username: "alice@example.com", Name: "Alice"}
+	f.users["2"] = &client.User{ID: 2, UUID: "user-uuid-2", Username: "bob@example.com", Name: "Bob"}
+}
+
+func withOwnedObjects(f *fakeTenable) {
+	f.scans = []client.Scan{
+		{ID: 100, Name: "Weekly", Owner: "bob@example.com"},
+		{ID: 101, Name: "Other", Owner: "alice@example.com"},
+	}
+	f.policies = []client.Policy{{ID: 200, Name: "Basic network scan", Owner: "bob@example.com"}}
+	f.objectACLs[objectKey(client.ObjectTypeScan, 100)] = []client.ACL{
+		{Type: "default", Permissions: 16},
+		{Type: aclTypeUser, ID: 2, Name: "bob@example.com", Owner: 1, Permissions: aclOwnerPermissions},
+	}
+	f.credentials["cred-1"] = &client.ManagedCredential{
+		UUID:        "cred-1",
+		CreatedBy:   &client.CredentialCreator{ID: 2},
+		Permissions: []client.CredentialPermission{{GranteeUUID: "user-uuid-2", Type: aclTypeUser, Permissions: credentialCanEdit}},
+	}
+}
+
+func TestUserDelete(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "deletes a user owning nothing",
+			wantCalls: []string{"DeleteUser"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.NotContains(t, f.users, "2")
+			},
+		},
+		{
+			name:           "transfers owned objects before deleting",
+			connectorOpts:  []Option{WithOwnershipTransferTo("alice@example.com")},
+			setup:          withOwnedObjects,
+			wantAnnotation: &structpb.Struct{},
+			wantCalls: []string{
+				"UpdateObjectPermissions", "UpdateObjectPermissions", "UpdateCredentialPermissions", "DeleteUser",
+			},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, []client.ACL{
+					{Type: "default", Permissions: 16},
+					{Type: aclTypeUser, ID: 1, Name: "alice@example.com", Owner: 1, Permissions: aclOwnerPermissions},
+				}, f.objectACLs[objectKey(client.ObjectTypeScan, 100)])
+				require.Equal(t, []client.ACL{
+					{Type: aclTypeUser, ID: 1, Name: "alice@example.com", Owner: 1, Permissions: aclOwnerPermissions},
+				}, f.objectACLs[objectKey(client.ObjectTypePolicy, 200)])
+				require.Equal(t, []client.CredentialPermission{
+					{GranteeUUID: "user-uuid-1", Type: aclTypeUser, Permissions: credentialCanEdit, Name: "alice@example.com"},
+				}, f.credentials["cred-1"].Permissions)
+				require.NotContains(t, f.users, "2")
+			},
+		},
+		{
+			name:           "transfer user can be given by uuid",
+			connectorOpts:  []Option{WithOwnershipTransferTo("user-uuid-1")},
+			setup:          func(f *fakeTenable) { f.policies = []client.Policy{{ID: 200, Owner: "bob@example.com"}} },
+			wantAnnotation: &structpb.Struct{},
+			wantCalls:      []string{"UpdateObjectPermissions", "DeleteUser"},
+		},
+		{
+			name:    "owned objects without a transfer user",
+			setup:   withOwnedObjects,
+			wantErr: errOwnershipTransferRequired,
+		},
+		{
+			name:          "transfer user does not exist",
+			connectorOpts: []Option{WithOwnershipTransferTo("carol@example.com")},
+			setup:         withOwnedObjects,
+			wantErr:       errOwnershipTargetNotFound,
+		},
+		{
+			name:          "transfer user is the deleted user",
+			connectorOpts: []Option{WithOwnershipTransferTo("bob@example.com")},
+			setup:         withOwnedObjects,
+			wantErr:       errOwnershipTargetDeleted,
+		},
+		{
+			name: "user was already deleted",
+			setup: func(f *fakeTenable) {
+				delete(f.users, "2")
+			},
+		},
+		{
+			name:          "transfer fails",
+			connectorOpts: []Option{WithOwnershipTransferTo("alice@example.com")},
+			setup: func(f *fakeTenable) {
+				withOwnedObjects(f)
+				f.failures["UpdateCredentialPermissions"] = errAPI
+			},
+			wantErr:   errAPI,
+			wantCalls: []string{"UpdateObjectPermissions", "UpdateObjectPermissions", "UpdateCredentialPermissions"},
+		},
+		{
+			name:      "deleting the user fails",
+			setup:     func(f *fakeTenable) { f.failures["DeleteUser"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"DeleteUser"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedUsers, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		return newUserBuilder(c.client, c).Delete(ctx, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "2"})
+	})
+}
+
+func TestUserDeleteReport(t *testing.T) {
+	f := newFakeTenable()
+	seedUsers(f)
+	withOwnedObjects(f)
+	c := &Connector{client: f}
+	WithOwnershipTransferTo("alice@example.com")(c)
+
+	annos, err := newUserBuilder(f, c).Delete(context.Background(), &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "2"})
+	require.NoError(t, err)
+
+	report := &structpb.Struct{}
+	ok, err := annos.Pick(report)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, map[string]any{
+		"transferred_from": "user-uuid-2",
+		"transferred_to":   "user-uuid-1",
+		"scans":            []any{"100"},
+		"policies":         []any{"200"},
+		"credentials":      []any{"cred-1"},
+	}, report.AsMap())
+}
+
+func TestUserRotate(t *testing.T) {
+	errAPI := errors.New("api down")
+	randomPassword := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{
+			RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 16},
+		},
+	}
+	testCases := []struct {
+		name      string
+		userId    string
+		options   *v2.CredentialOptions
+		setup     func(f *fakeTenable)
+		wantErr   error
+		wantCalls []string
+	}{
+		{
+			name:      "sets a new random password",
+			userId:    "2",
+			options:   randomPassword,
+			wantCalls: []string{"ChangeUserPassword"},
+		},
+		{
+			name:    "user does not exist",
+			userId:  "3",
+			options: randomPassword,
+			wantErr: client.ErrNotFound,
+		},
+		{
+			name:    "user can only log in through SAML",
+			userId:  "2",
+			options: randomPassword,
+			setup: func(f *fakeTenable) {
+				f.authorizations["2"] = &client.UserAuthorizations{UserUUID: "user-uuid-2", SAMLPermitted: true}
+			},
+			wantErr: errPasswordLoginNotPermitted,
+		},
+		{
+			name:    "getting the authorizations fails",
+			userId:  "2",
+			options: randomPassword,
+			setup:   func(f *fakeTenable) { f.failures["GetUserAuthorizations"] = errAPI },
+			wantErr: errAPI,
+		},
+		{
+			name:      "changing the password fails",
+			userId:    "2",
+			options:   randomPassword,
+			setup:     func(f *fakeTenable) { f.failures["ChangeUserPassword"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"ChangeUserPassword"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeTenable()
+			seedUsers(f)
+			f.authorizations["2"] = &client.UserAuthorizations{UserUUID: "user-uuid-2", PasswordPermitted: true, SAMLPermitted: true}
+			if tc.setup != nil {
+				tc.setup(f)
+			}
+
+			plaintexts, annos, err := newUserBuilder(f, &Connector{client: f}).Rotate(
+				context.Background(),
+				&v2.ResourceId{ResourceType: userResourceType.Id, Resource: tc.userId},
+				tc.options,
+			)
+			require.Empty(t, annos)
+			require.Equal(t, tc.wantCalls, f.calls)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Empty(t, plaintexts)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, plaintexts, 1)
+			require.Equal(t, "password", plaintexts[0].GetName())
+			require.Equal(t, f.passwords[tc.userId], string(plaintexts[0].GetBytes()))
+			require.True(t, isPasswordValid(f.passwords[tc.userId], DefaultPasswordPolicy()))
+		})
+	}
+}
+
+func TestUserRotateUnsupportedOption(t *testing.T) {
+	f := newFakeTenable()
+	seedUsers(f)
+
+	_, _, err := newUserBuilder(f, &Connector{client: f}).Rotate(
+		context.Background(),
+		&v2.ResourceId{ResourceType: userResourceType.Id, Resource: "2"},
+		&v2.CredentialOptions{Options: &v2.CredentialOptions_NoPassword_{NoPassword: &v2.CredentialOptions_NoPassword{}}},
+	)
+	require.Error(t, err)
+	require.Empty(t, f.calls)
+}
+
+func TestCreateAccountCapabilityDetails(t *testing.T) {
+	f := newFakeTenable()
+	c := &Connector{client: f}
+	WithPasswordPolicy(PasswordPolicy{MinLength: 20, ExcludedCharacters: "0O"})(c)
+
+	_, annos, err := newUserBuilder(f, c).CreateAccountCapabilityDetails(context.Background())
+	require.NoError(t, err)
+
+	policy := &structpb.Struct{}
+	ok, err := annos.Pick(policy)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, map[string]any{
+		"min_length":          float64(20),
+		"required_classes":    []any{"upper", "lower", "digit", "symbol"},
+		"excluded_characters": "0O",
+	}, policy.AsMap())
+}
+
+func seedAccounts(f *fakeTenable) {
+	seedUsers(f)
+	f.roles = []*client.RoleDetails{{UUID: uuid.MustParse(testRoleUUID), Name: "Scanner", Type: "CUSTOM"}}
+	f.groups["10"] = &client.Group{ID: 10, UUID: "group-uuid-10", Name: "Scanners"}
+	f.groups["11"] = &client.Group{ID: 11, UUID: "group-uuid-11", Name: "Auditors"}
+}
+
+func TestCreateAccount(t *testing.T) {
+	errAPI := errors.New("api down")
+	randomPassword := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{
+			RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 12},
+		},
+	}
+	testCases := []struct {
+		name         string
+		profile      map[string]any
+		options      *v2.CredentialOptions
+		setup        func(f *fakeTenable)
+		wantErr      bool
+		wantCalls    []string
+		wantPassword bool
+		check        func(t *testing.T, f *fakeTenable)
+	}{
+		{
+			name:         "creates a basic user named after the email",
+			profile:      map[string]any{"name": "Carol", "email": "carol@example.com"},
+			wantCalls:    []string{"CreateUser"},
+			wantPassword: true,
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, "carol@example.com", f.users["3"].Username)
+				require.Equal(t, BasicUserRole, f.users["3"].Permissions)
+			},
+		},
+		{
+			name: "applies username, permission level and groups",
+			profile: map[string]any{
+				"name":        "Carol",
+				"email":       "carol@example.com",
+				"username":    "carol",
+				"permissions": float64(StandardUserRole),
+				"groups":      []any{"Scanners", "11"},
+			},
+			wantCalls:    []string{"CreateUser", "CreateUserGroupMembership", "CreateUserGroupMembership"},
+			wantPassword: true,
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, "carol", f.users["3"].Username)
+				require.Equal(t, StandardUserRole, f.users["3"].Permissions)
+				require.Equal(t, []string{"3"}, f.groupMembers["10"])
+				require.Equal(t, []string{"3"}, f.groupMembers["11"])
+			},
+		},
+		{
+			name:         "assigns a role by name",
+			profile:      map[string]any{"name": "Carol", "email": "carol@example.com", "role": "scanner"},
+			wantCalls:    []string{"CreateUser", "UpdateUserRoles"},
+			wantPassword: true,
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, []string{testRoleUUID}, f.userRoles["user-uuid-3"])
+			},
+		},
+		{
+			name:      "saml only users get no password",
+			profile:   map[string]any{"name": "Carol", "email": "carol@example.com", "saml_only": true},
+			wantCalls: []string{"CreateUser", "UpdateUserAuthorizations"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, &client.UserAuthorizations{SAMLPermitted: true}, f.authorizations["3"])
+			},
+		},
+		{
+			name:    "the sso credential option makes the user saml only",
+			profile: map[string]any{"name": "Carol", "email": "carol@example.com"},
+			options: &v2.CredentialOptions{
+				Options: &v2.CredentialOptions_Sso{Sso: &v2.CredentialOptions_SSO{}},
+			},
+			wantCalls: []string{"CreateUser", "UpdateUserAuthorizations"},
+		},
+		{
+			name:    "unknown group is rejected before creating the user",
+			profile: map[string]any{"name": "Carol", "email": "carol@example.com", "groups": []any{"Nobody"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown permission level",
+			profile: map[string]any{"name": "Carol", "email": "carol@example.com", "permissions": float64(48)},
+			wantErr: true,
+		},
+		{
+			name: "permission level and role together",
+			profile: map[string]any{
+				"name": "Carol", "email": "carol@example.com", "permissions": float64(StandardUserRole), "role": "Scanner",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "rolls back when adding a group fails",
+			profile: map[string]any{"name": "Carol", "email": "carol@example.com", "groups": []any{"Scanners"}},
+			setup: func(f *fakeTenable) {
+				f.failures["CreateUserGroupMembership"] = errAPI
+			},
+			wantErr:   true,
+			wantCalls: []string{"CreateUser", "CreateUserGroupMembership", "DeleteUser"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.NotContains(t, f.users, "3")
+			},
+		},
+		{
+			name:    "rolls back when restricting logins fails",
+			profile: map[string]any{"name": "Carol", "email": "carol@example.com", "role": testRoleUUID, "saml_only": true},
+			setup: func(f *fakeTenable) {
+				f.failures["UpdateUserAuthorizations"] = errAPI
+			},
+			wantErr:   true,
+			wantCalls: []string{"CreateUser", "UpdateUserRoles", "UpdateUserAuthorizations", "DeleteUser"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeTenable()
+			seedAccounts(f)
+			if tc.setup != nil {
+				tc.setup(f)
+			}
+			options := tc.options
+			if options == nil {
+				options = randomPassword
+			}
+			profile, err := structpb.NewStruct(tc.profile)
+			require.NoError(t, err)
+
+			res, plaintexts, _, err := newUserBuilder(f, &Connector{client: f}).CreateAccount(
+				context.Background(), &v2.AccountInfo{Profile: profile}, options)
+			require.Equal(t, tc.wantCalls, f.calls)
+			if tc.check != nil {
+				tc.check(t, f)
+			}
+			if tc.wantErr {
+				require.Error(t, err)
+				require.Nil(t, res)
+				return
+			}
+			require.NoError(t, err)
+			require.IsType(t, &v2.CreateAccountResponse_SuccessResult{}, res)
+			if tc.wantPassword {
+				require.Len(t, plaintexts, 1)
+				require.True(t, isPasswordValid(string(plaintexts[0].GetBytes()), DefaultPasswordPolicy()))
+			} else {
+				require.Empty(t, plaintexts)
+			}
+		})
+	}
+}
+
+func TestCreateAccountExistingUser(t *testing.T) {
+	conflict := &client.APIError{StatusCode: http.StatusConflict, Method: http.MethodPost, Path: client.BaseUsersPath}
+	testCases := []struct {
+		name      string
+		profile   map[string]any
+		setup     func(f *fakeTenable, c *Connector)
+		wantCalls []string
+		wantUser  string
+	}{
+		{
+			name:     "username already exists",
+			profile:  map[string]any{"name": "Bob", "email": "robert@example.com", "username": "BOB@example.com"},
+			wantUser: "2",
+		},
+		{
+			name:    "the connector's users are stale",
+			profile: map[string]any{"name": "Bob", "email": "bob@example.com"},
+			setup: func(f *fakeTenable, c *Connector) {
+				c.cachedUsers = map[string]*client.User{"user-uuid-1": f.users["1"]}
+				c.usersTimestamp = time.Now()
+			},
+			wantUser: "2",
+		},
+		{
+			name:     "email already exists",
+			profile:  map[string]any{"name": "Bob", "email": "Bob@Example.com", "username": "robert"},
+			setup:    func(f *fakeTenable, _ *Connector) { f.users["2"].Email = "bob@example.com" },
+			wantUser: "2",
+		},
+		{
+			name:    "created concurrently after the users were read",
+			profile: map[string]any{"name": "Bob", "email": "bob@example.com"},
+			setup: func(f *fakeTenable, _ *Connector) {
+				bob := f.users["2"]
+				delete(f.users, "2")
+				f.onCall = func(method string) {
+					if method == "CreateUser" {
+						f.users["2"] = bob
+					}
+				}
+				f.failures["CreateUser"] = conflict
+			},
+			wantCalls: []string{"CreateUser"},
+			wantUser:  "2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeTenable()
+			seedAccounts(f)
+			c := &Connector{client: f}
+			if tc.setup != nil {
+				tc.setup(f, c)
+			}
+			profile, err := structpb.NewStruct(tc.profile)
+			require.NoError(t, err)
+
+			res, plaintexts, _, err := newUserBuilder(f, c).CreateAccount(context.Background(), &v2.AccountInfo{Profile: profile}, &v2.CredentialOptions{
+				Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 12}},
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantCalls, f.calls)
+			require.Empty(t, plaintexts)
+
+			success, ok := res.(*v2.CreateAccountResponse_SuccessResult)
+			require.True(t, ok)
+			require.False(t, success.GetIsCreateAccountResult())
+			require.Equal(t, tc.wantUser, success.GetResource().GetId().GetResource())
+		})
+	}
+}
+
+func TestCreateAccountRetried(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedAccounts(f)
+	b := newUserBuilder(f, &Connector{client: f})
+	profile, err := structpb.NewStruct(map[string]any{"name": "Carol", "email": "carol@example.com"})
+	require.NoError(t, err)
+	options := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 12}},
+	}
+
+	first, plaintexts, _, err := b.CreateAccount(ctx, &v2.AccountInfo{Profile: profile}, options)
+	require.NoError(t, err)
+	require.True(t, first.GetIsCreateAccountResult())
+	require.Len(t, plaintexts, 1)
+
+	second, plaintexts, _, err := b.CreateAccount(ctx, &v2.AccountInfo{Profile: profile}, options)
+	require.NoError(t, err)
+	require.False(t, second.GetIsCreateAccountResult())
+	require.Empty(t, plaintexts)
+	require.Equal(t, []string{"CreateUser"}, f.calls)
+	require.Len(t, f.users, 3)
+}
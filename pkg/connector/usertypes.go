@@ -0,0 +1,200 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// Legacy permission levels held in client.User.Permissions, next to BasicUserRole and AdministratorUserRole.
+const (
+	ScanOperatorUserRole = 24
+	StandardUserRole     = 32
+	ScanManagerUserRole  = 40
+)
+
+var errBasicUserTypeRevoke = errors.New("baton-tenable-vm: cannot revoke the Basic user type, grant another user type instead")
+
+// userType is a legacy Tenable role. Every user holds exactly one of them.
+type userType struct {
+	permissions int
+	name        string
+}
+
+var userTypes = []userType{
+	{permissions: BasicUserRole, name: "Basic"},
+	{permissions: ScanOperatorUserRole, name: "Scan Operator"},
+	{permissions: StandardUserRole, name: "Standard"},
+	{permissions: ScanManagerUserRole, name: "Scan Manager"},
+	{permissions: AdministratorUserRole, name: "Administrator"},
+}
+
+// parseUserType returns the permission level a user_type resource id stands for.
+func parseUserType(resourceId string) (int, error) {
+	permissions, err := strconv.Atoi(resourceId)
+	if err == nil {
+		for _, ut := range userTypes {
+			if ut.permissions == permissions {
+				return permissions, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("baton-tenable-vm: unknown user type %s", resourceId)
+}
+
+type userTypeBuilder struct {
+	client    client.TenableAPI
+	connector *Connector
+}
+
+func (o *userTypeBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return userTypeResourceType
+}
+
+// List returns the fixed set of legacy permission levels.
+func (o *userTypeBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+	for _, ut := range userTypes {
+		profile := map[string]interface{}{
+			"name":        ut.name,
+			"permissions": ut.permissions,
+		}
+		userTypeResource, err := rs.NewRoleResource(
+			ut.name,
+			userTypeResourceType,
+			ut.permissions,
+			[]rs.RoleTraitOption{rs.WithRoleProfile(profile)},
+			rs.WithParentResourceID(parentResourceID),
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, userTypeResource)
+	}
+	return resources, "", nil, nil
+}
+
+func (o *userTypeBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	displayName := fmt.Sprintf("%s user type %s", resource.DisplayName, assignedEntitlement)
+	description := fmt.Sprintf("Has the legacy %s user type", resource.DisplayName)
+	entitlements := []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			assignedEntitlement,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDescription(description),
+			entitlement.WithDisplayName(displayName),
+		),
+	}
+
+	return entitlements, "", nil, nil
+}
+
+// Grants returns a grant for every user whose permissions match the level.
+func (o *userTypeBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	permissions, err := parseUserType(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	users, annos, err := o.connector.cacheUsers(ctx)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("failed to cache users: %w", err)
+	}
+
+	var grants []*v2.Grant
+	for _, user := range users {
+		if user.Permissions != permissions {
+			continue
+		}
+		userResourceID := &v2.ResourceId{
+			ResourceType: userResourceType.Id,
+			Resource:     strconv.Itoa(user.ID),
+		}
+		grants = append(grants, grant.NewGrant(resource, assignedEntitlement, userResourceID))
+	}
+	return grants, "", nil, nil
+}
+
+// Grant sets the user's permissions to the level, replacing the user type the user held before.
+func (o *userTypeBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	userId := principal.Id.Resource
+	permissions, err := parseUserType(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := o.client.GetUserDetails(client.WithoutCache(ctx), userId)
+	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			return nil, fmt.Errorf("baton-tenable-vm: cannot grant user type %d, user %s no longer exists: %w", permissions, userId, err)
+		}
+		return nil, fmt.Errorf("baton-tenable-vm: failed to get user %s: %w", userId, err)
+	}
+	if user.Permissions == permissions {
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	return nil, o.setPermissions(ctx, user, permissions)
+}
+
+// Revoke drops the user back to the Basic user type.
+func (o *userTypeBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	userId := grant.Principal.Id.Resource
+	permissions, err := parseUserType(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := o.client.GetUserDetails(client.WithoutCache(ctx), userId)
+	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			l.Debug("User no longer exists, user type already revoked", zap.String("user_id", userId))
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+		return nil, fmt.Errorf("baton-tenable-vm: failed to get user %s: %w", userId, err)
+	}
+	if user.Permissions != permissions {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+	if permissions == BasicUserRole {
+		return nil, fmt.Errorf("%w: user %s", errBasicUserTypeRevoke, userId)
+	}
+
+	return nil, o.setPermissions(ctx, user, BasicUserRole)
+}
+
+func (o *userTypeBuilder) setPermissions(ctx context.Context, user *client.User, permissions int) error {
+	userId := strconv.Itoa(user.ID)
+	_, err := o.client.UpdateUser(ctx, userId, client.UserUpdateReqBody{Permissions: permissions})
+	if err != nil {
+		return fmt.Errorf("baton-tenable-vm: failed to set permissions of user %s to %d: %w", userId, permissions, err)
+	}
+	o.connector.invalidateUsersCache()
+
+	ctxzap.Extract(ctx).Debug("User type updated",
+		zap.String("user_id", userId),
+		zap.Int("from", user.Permissions),
+		zap.Int("to", permissions),
+	)
+	return nil
+}
+
+func newUserTypeBuilder(c client.TenableAPI, conn *Connector) *userTypeBuilder {
+	return &userTypeBuilder{
+		client:    c,
+		connector: conn,
+	}
+}
@@ -0,0 +1,140 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-tenable-vm/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+func seedUserTypes(f *fakeTenable) {
+	f.users["1"] = &client.User{ID: 1, UUID: "user-uuid-1", Name: "Alice", Permissions: AdministratorUserRole}
+	f.users["2"] = &client.User{ID: 2, UUID: "user-uuid-2", Name: "Bob", Permissions: StandardUserRole}
+	f.users["3"] = &client.User{ID: 3, UUID: "user-uuid-3", Name: "Carol", Permissions: BasicUserRole}
+}
+
+func TestUserTypeGrants(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTenable()
+	seedUserTypes(f)
+	b := newUserTypeBuilder(f, &Connector{client: f})
+
+	resources, _, _, err := b.List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, len(userTypes))
+
+	got := make(map[string][]string)
+	for _, resource := range resources {
+		grants, _, _, err := b.Grants(ctx, resource, &pagination.Token{})
+		require.NoError(t, err)
+		for _, g := range grants {
+			got[resource.Id.Resource] = append(got[resource.Id.Resource], g.Principal.Id.Resource)
+		}
+	}
+	require.Equal(t, map[string][]string{
+		strconv.Itoa(AdministratorUserRole): {"1"},
+		strconv.Itoa(StandardUserRole):      {"2"},
+		strconv.Itoa(BasicUserRole):         {"3"},
+	}, got)
+}
+
+func TestUserTypeGrant(t *testing.T) {
+	errAPI := errors.New("api down")
+	testCases := []provisioningTestCase{
+		{
+			name:      "replaces the user's permission level",
+			wantCalls: []string{"UpdateUser"},
+			check: func(t *testing.T, f *fakeTenable) {
+				require.Equal(t, ScanManagerUserRole, f.users["2"].Permissions)
+			},
+		},
+		{
+			name:           "user already has the level",
+			setup:          func(f *fakeTenable) { f.users["2"].Permissions = ScanManagerUserRole },
+			wantAnnotation: &v2.GrantAlreadyExists{},
+		},
+		{
+			name:    "user does not exist",
+			setup:   func(f *fakeTenable) { delete(f.users, "2") },
+			wantErr: client.ErrNotFound,
+		},
+		{
+			name:      "updating the user fails",
+			setup:     func(f *fakeTenable) { f.failures["UpdateUser"] = errAPI },
+			wantErr:   errAPI,
+			wantCalls: []string{"UpdateUser"},
+		},
+	}
+
+	runProvisioningTestCases(t, testCases, seedUserTypes, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+		userType := newTestResource(userTypeResourceType, strconv.Itoa(ScanManagerUserRole))
+		return newUserTypeBuilder(c.client, c).Grant(ctx, newTestResource(userResourceType, "2"), newTestEntitlement(userType, assignedEntitlement))
+	})
+}
+
+func TestUserTypeRevoke(t *testing.T) {
+	testCases := []struct {
+		provisioningTestCase
+		level  int
+		userId string
+	}{
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:      "falls back to basic",
+				wantCalls: []string{"UpdateUser"},
+				check: func(t *testing.T, f *fakeTenable) {
+					require.Equal(t, BasicUserRole, f.users["1"].Permissions)
+				},
+			},
+			level:  AdministratorUserRole,
+			userId: "1",
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:           "user has another level",
+				wantAnnotation: &v2.GrantAlreadyRevoked{},
+			},
+			level:  AdministratorUserRole,
+			userId: "2",
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:           "user was deleted",
+				wantAnnotation: &v2.GrantAlreadyRevoked{},
+			},
+			level:  AdministratorUserRole,
+			userId: "4",
+		},
+		{
+			provisioningTestCase: provisioningTestCase{
+				name:    "basic cannot be revoked",
+				wantErr: errBasicUserTypeRevoke,
+			},
+			level:  BasicUserRole,
+			userId: "3",
+		},
+	}
+
+	for _, tc := range testCases {
+		runProvisioningTestCases(t, []provisioningTestCase{tc.provisioningTestCase}, seedUserTypes, func(ctx context.Context, c *Connector) (annotations.Annotations, error) {
+			userType := newTestResource(userTypeResourceType, strconv.Itoa(tc.level))
+			return newUserTypeBuilder(c.client, c).Revoke(ctx, &v2.Grant{
+				Principal:   newTestResource(userResourceType, tc.userId),
+				Entitlement: newTestEntitlement(userType, assignedEntitlement),
+			})
+		})
+	}
+}
+
+func TestUserTypeUnknownLevel(t *testing.T) {
+	_, err := parseUserType("48")
+	require.Error(t, err)
+	_, err = parseUserType("admin")
+	require.Error(t, err)
+}